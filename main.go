@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"os"
 	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/brsyuksel/shellican/pkg/core"
 	"github.com/spf13/cobra"
@@ -189,25 +193,397 @@ var runCmd = &cobra.Command{
 		scriptName := args[1]
 		scriptArgs := args[2:]
 
-		ctx, err := core.ResolveCommand(collection, []string{scriptName})
+		assignments, _ := cmd.Flags().GetStringArray("env")
+		envFiles, _ := cmd.Flags().GetStringArray("env-file")
+		overrides, err := core.ParseEnvOverrides(assignments, envFiles)
+		if err != nil {
+			fmt.Printf("Error parsing environment: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, err := core.ResolveCommandUnchecked(collection, []string{scriptName}, overrides)
 		if err != nil {
 			fmt.Printf("Error resolving command: %v\n", err)
 			os.Exit(1)
 		}
 
-		if err := core.ExecuteContext(ctx, scriptArgs); err != nil {
+		matrixAssignments, _ := cmd.Flags().GetStringArray("matrix")
+		matrix, err := core.ParseMatrix(matrixAssignments)
+		if err != nil {
+			fmt.Printf("Error parsing matrix: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx.AssumeYes, _ = cmd.Flags().GetBool("yes")
+		ctx.Force, _ = cmd.Flags().GetBool("force")
+		ctx.Sandbox, _ = cmd.Flags().GetBool("sandbox")
+		ctx.EnvAssignments = assignments
+		ctx.EnvFiles = envFiles
+
+		if logRun, _ := cmd.Flags().GetBool("log"); logRun {
+			ctx.Config.Log.Enabled = true
+		}
+
+		// a dry run reports missing requirements in its plan
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON && !dryRun {
+			fmt.Printf("Error: --json requires --dry-run\n")
+			os.Exit(1)
+		}
+		if !dryRun {
+			if err := core.CheckRequirements(ctx, matrix); err != nil {
+				fmt.Printf("Error resolving command: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if dryRun {
+			plan, err := core.ExplainContextWithMatrix(ctx, scriptArgs, matrix)
+			if err != nil {
+				fmt.Printf("Error explaining command: %v\n", err)
+				os.Exit(1)
+			}
+			if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+				err = core.PrintPlanJSON(os.Stdout, plan)
+			} else {
+				err = core.PrintPlan(os.Stdout, plan)
+			}
+			if err != nil {
+				fmt.Printf("Error printing plan: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if detach, _ := cmd.Flags().GetBool("detach"); detach {
+			exe, err := os.Executable()
+			if err != nil {
+				fmt.Printf("Error detaching: failed to locate executable: %v\n", err)
+				os.Exit(1)
+			}
+			run, err := core.Detach(ctx, scriptArgs, core.DetachedCommand(exe, cmd.Flags(), args))
+			if err != nil {
+				fmt.Printf("Error detaching: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Started background run %d (pid %d), see 'shellican logs %d' and 'shellican stop %d'\n", run.ID, run.PID, run.ID, run.ID)
+			return
+		}
+
+		if watch, _ := cmd.Flags().GetBool("watch"); watch {
+			if core.HasMatrix(ctx, matrix) {
+				fmt.Printf("Error: matrix runs cannot be watched\n")
+				os.Exit(1)
+			}
+			if err := core.WatchContext(ctx, scriptArgs); err != nil {
+				fmt.Printf("Error watching runnable: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if core.HasMatrix(ctx, matrix) {
+			if events, _ := cmd.Flags().GetString("events"); events != "" {
+				fmt.Printf("Error: matrix runs do not report events\n")
+				os.Exit(1)
+			}
+			jobs, _ := cmd.Flags().GetInt("jobs")
+			if _, err := core.RunMatrix(ctx, scriptArgs, matrix, jobs); err != nil {
+				fmt.Printf("Error executing matrix: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		observer, err := eventsObserver(cmd)
+		if err != nil {
+			fmt.Printf("Error opening event stream: %v\n", err)
+			os.Exit(1)
+		}
+		if err := core.ExecuteContextObserved(ctx, scriptArgs, observer); err != nil {
 			fmt.Printf("Error executing script: %v\n", err)
 			os.Exit(1)
 		}
 	},
 }
 
+// eventsObserver returns the observer requested with --events, or nil.
+func eventsObserver(cmd *cobra.Command) (core.Observer, error) {
+	format, _ := cmd.Flags().GetString("events")
+	if format == "" {
+		return nil, nil
+	}
+	if format != "jsonl" {
+		return nil, fmt.Errorf("unsupported event format '%s': expected jsonl", format)
+	}
+
+	fd, _ := cmd.Flags().GetInt("events-fd")
+	f := os.NewFile(uintptr(fd), "events")
+	if f == nil {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("file descriptor %d is not open: %w", fd, err)
+	}
+	return core.NewJSONLinesObserver(&eventsWriter{f: f, fd: fd}), nil
+}
+
+// eventsWriter writes events to f, reporting the first write that fails.
+type eventsWriter struct {
+	f    *os.File
+	fd   int
+	once sync.Once
+}
+
+func (w *eventsWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	if err != nil {
+		w.once.Do(func() {
+			fmt.Printf("Warning: failed to write events to file descriptor %d: %v\n", w.fd, err)
+		})
+	}
+	return n, err
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history [collection] [runnable]",
+	Short: "Show the run history",
+	Long: `Show the run history.
+  If collection is provided, only runs of that collection are shown.
+  If runnable is also provided, only runs of that runnable are shown.`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+		failed, _ := cmd.Flags().GetBool("failed")
+		since, _ := cmd.Flags().GetDuration("since")
+
+		filter := core.HistoryFilter{Limit: limit, Failed: failed}
+		if len(args) > 0 {
+			filter.Collection = args[0]
+		}
+		if len(args) > 1 {
+			filter.Runnable = args[1]
+		}
+		if since > 0 {
+			filter.Since = time.Now().Add(-since)
+		}
+
+		if err := core.ListHistory(filter); err != nil {
+			fmt.Printf("Error listing history: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var rerunCmd = &cobra.Command{
+	Use:   "rerun <id>",
+	Short: "Replay a run from the history",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Printf("Error: invalid history id: %s\n", args[0])
+			os.Exit(1)
+		}
+
+		entry, err := core.FindHistoryEntry(id)
+		if err != nil {
+			fmt.Printf("Error reading history: %v\n", err)
+			os.Exit(1)
+		}
+
+		yes, _ := cmd.Flags().GetBool("yes")
+		if err := core.Rerun(entry, yes); err != nil {
+			fmt.Printf("Error executing script: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var lastCmd = &cobra.Command{
+	Use:   "last",
+	Short: "Replay the most recent run",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entry, err := core.LastHistoryEntry()
+		if err != nil {
+			fmt.Printf("Error reading history: %v\n", err)
+			os.Exit(1)
+		}
+
+		yes, _ := cmd.Flags().GetBool("yes")
+		if err := core.Rerun(entry, yes); err != nil {
+			fmt.Printf("Error executing script: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <collection> <runnable> | logs <id>",
+	Short: "Show captured output of a runnable or a background run",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		list, _ := cmd.Flags().GetBool("list")
+		follow, _ := cmd.Flags().GetBool("follow")
+
+		if len(args) == 1 {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				fmt.Printf("Error: invalid background run id: %s\n", args[0])
+				os.Exit(1)
+			}
+			if err := core.ShowDetachedLogs(id, follow); err != nil {
+				fmt.Printf("Error showing logs: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if err := core.ShowLogs(args[0], args[1], list, follow); err != nil {
+			fmt.Printf("Error showing logs: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status <collection> [runnable]",
+	Short: "Show whether runnables with sources are up to date",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var name string
+		if len(args) > 1 {
+			name = args[1]
+		}
+
+		if err := core.ShowStatus(args[0], name); err != nil {
+			fmt.Printf("Error showing status: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var schedulerCmd = &cobra.Command{
+	Use:   "scheduler",
+	Short: "Run scheduled runnables in the foreground",
+	Long: `Run scheduled runnables in the foreground.
+  Runnables declaring a cron expression in 'schedule' are started at their scheduled time.
+  A runnable is not started again while its previous run is still active.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if list, _ := cmd.Flags().GetBool("list"); list {
+			if err := core.ListSchedules(); err != nil {
+				fmt.Printf("Error listing schedules: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if err := core.RunScheduler(); err != nil {
+			fmt.Printf("Error running scheduler: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var pipeCmd = &cobra.Command{
+	Use:   "pipe <collection>/<runnable>... [-- args...]",
+	Short: "Pipe the output of runnables into each other",
+	Long: `Pipe the output of runnables into each other.
+  Every runnable runs with its own environment and working directory.
+  Arguments after -- are passed to the first runnable.
+  The pipeline fails if any runnable fails, and the exit status of each is reported.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		refs, stageArgs := args, []string(nil)
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			refs, stageArgs = args[:dash], args[dash:]
+		}
+		for _, ref := range refs {
+			if !strings.Contains(ref, "/") {
+				fmt.Printf("Error: invalid pipeline stage '%s': expected <collection>/<runnable>\n", ref)
+				os.Exit(1)
+			}
+		}
+
+		stages, err := core.ResolvePipeline("", refs)
+		if err != nil {
+			fmt.Printf("Error resolving pipeline: %v\n", err)
+			os.Exit(1)
+		}
+
+		yes, _ := cmd.Flags().GetBool("yes")
+		if _, err := core.RunPipeline(stages, stageArgs, yes); err != nil {
+			fmt.Fprintf(os.Stderr, "Error executing pipeline: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var psCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List background runs",
+	Long: `List background runs started with 'run --detach'.
+  Records of runs that exited more than a day ago are removed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		clean, _ := cmd.Flags().GetBool("clean")
+		if err := core.ShowDetached(clean); err != nil {
+			fmt.Printf("Error listing background runs: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop <id>",
+	Short: "Stop a background run",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Printf("Error: invalid background run id: %s\n", args[0])
+			os.Exit(1)
+		}
+
+		if err := core.StopDetached(id); err != nil {
+			fmt.Printf("Error stopping background run: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
 func init() {
 	// Disable completion command
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
 	// Add flags
 	showCmd.Flags().Bool("readme", false, "Show README content")
+	runCmd.Flags().Bool("dry-run", false, "Print what would be executed without running anything")
+	runCmd.Flags().Bool("json", false, "Print the dry-run plan as JSON")
+	runCmd.Flags().StringArrayP("env", "e", nil, "Set an environment variable (KEY=VALUE), overriding every other source")
+	runCmd.Flags().StringArray("env-file", nil, "Read environment variables from a file of KEY=VALUE lines")
+	runCmd.Flags().Bool("log", false, "Capture output to a log file")
+	runCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompts")
+	runCmd.Flags().Bool("force", false, "Run even if sources are unchanged")
+	runCmd.Flags().Bool("sandbox", false, "Sandbox the run even if the runnable does not ask for it (Linux only)")
+	runCmd.Flags().Bool("watch", false, "Rerun whenever watched files change")
+	runCmd.Flags().String("events", "", "Write run events in the given format (jsonl) to --events-fd")
+	runCmd.Flags().Int("events-fd", 3, "File descriptor receiving the events of --events")
+	runCmd.Flags().BoolP("detach", "d", false, "Run in the background, see 'ps', 'logs <id>' and 'stop <id>'")
+	runCmd.Flags().StringArray("matrix", nil, "Run once per value of a variable (KEY=a,b,c), overriding the matrix of the runnable")
+	runCmd.Flags().IntP("jobs", "j", 1, "Number of matrix combinations run at once")
+	logsCmd.Flags().Bool("list", false, "List log files instead of showing the latest")
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep printing output appended to the latest log")
+	rerunCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompts")
+	lastCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompts")
+	pipeCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompts")
+	psCmd.Flags().Bool("clean", false, "Remove the records of every exited run")
+	schedulerCmd.Flags().Bool("list", false, "List scheduled runnables and their next run")
+	historyCmd.Flags().Int("limit", 20, "Maximum number of entries to show (0 for all)")
+	historyCmd.Flags().Bool("failed", false, "Only show failed runs")
+	historyCmd.Flags().Duration("since", 0, "Only show runs started within this duration (e.g. 24h)")
 
 	// Add commands to root
 	rootCmd.AddCommand(versionCmd)
@@ -218,4 +594,13 @@ func init() {
 	rootCmd.AddCommand(importCmd)
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(rerunCmd)
+	rootCmd.AddCommand(lastCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(schedulerCmd)
+	rootCmd.AddCommand(pipeCmd)
+	rootCmd.AddCommand(psCmd)
+	rootCmd.AddCommand(stopCmd)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,19 +16,272 @@ type CollectionConfig struct {
 	Readme       string            `yaml:"readme"`
 	Runnables    []string          `yaml:"runnables"`
 	Environments map[string]string `yaml:"environments"`
+	EnvDefaults  map[string]string `yaml:"env_defaults,omitempty"`
+	Requires     RequiresConfig    `yaml:"requires,omitempty"`
+	InheritEnv   *bool             `yaml:"inherit_env,omitempty"`
+	PassEnv      []string          `yaml:"pass_env,omitempty"`
+	UnsetEnv     []string          `yaml:"unset_env,omitempty"`
+	BeforeEach   string            `yaml:"before_each,omitempty"`
+	AfterEach    string            `yaml:"after_each,omitempty"`
 }
 
 // RunnableConfig represents the configuration for a runnable.
 type RunnableConfig struct {
-	Name         string            `yaml:"name"`
-	Help         string            `yaml:"help"`
-	Readme       string            `yaml:"readme"`
+	Name         string                      `yaml:"name"`
+	Help         string                      `yaml:"help"`
+	Readme       string                      `yaml:"readme"`
+	Run          string                      `yaml:"run"`
+	Before       string                      `yaml:"before"`
+	After        string                      `yaml:"after"`
+	Environments map[string]string           `yaml:"environments"`
+	EnvDefaults  map[string]string           `yaml:"env_defaults"`
+	Log          LogConfig                   `yaml:"log"`
+	Confirm      ConfirmConfig               `yaml:"confirm"`
+	Requires     RequiresConfig              `yaml:"requires"`
+	InheritEnv   *bool                       `yaml:"inherit_env"`
+	PassEnv      []string                    `yaml:"pass_env"`
+	UnsetEnv     []string                    `yaml:"unset_env"`
+	Sources      []string                    `yaml:"sources"`
+	Outputs      []string                    `yaml:"outputs"`
+	Fingerprint  FingerprintConfig           `yaml:"fingerprint"`
+	Watch        []string                    `yaml:"watch"`
+	Schedule     string                      `yaml:"schedule"`
+	Limits       LimitsConfig                `yaml:"limits"`
+	Sandbox      SandboxConfig               `yaml:"sandbox"`
+	Service      ServiceConfig               `yaml:"service"`
+	WaitFor      []WaitCondition             `yaml:"wait_for"`
+	Pipeline     []string                    `yaml:"pipeline"`
+	Template     bool                        `yaml:"template"`
+	Platforms    []string                    `yaml:"platforms"`
+	Overrides    map[string]PlatformOverride `yaml:"overrides"`
+	Matrix       map[string][]string         `yaml:"matrix"`
+	Notify       NotifyConfig                `yaml:"notify"`
+	// SkipCollectionHooks opts out of the before_each and after_each hooks of the collection.
+	SkipCollectionHooks bool `yaml:"skip_collection_hooks"`
+}
+
+// PlatformOverride represents settings replacing those of a runnable on an os
+// or os/arch pair, such as "darwin" or "linux/arm64".
+type PlatformOverride struct {
 	Run          string            `yaml:"run"`
 	Before       string            `yaml:"before"`
 	After        string            `yaml:"after"`
 	Environments map[string]string `yaml:"environments"`
 }
 
+// NotifyConfig represents the notifications sent when a run finishes.
+// It can be given as a boolean (`notify: true`), which sends a desktop
+// notification, or as a mapping, which enables notifications unless
+// `enabled: false` is set. A mapping without any channel also sends a desktop
+// notification, unless `desktop: false` is set.
+type NotifyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// On is one of "success", "failure" or "always", the default.
+	On          string        `yaml:"on"`
+	MinDuration time.Duration `yaml:"min_duration"`
+	Desktop     bool          `yaml:"desktop"`
+	Bell        bool          `yaml:"bell"`
+	Webhook     string        `yaml:"webhook"`
+	Command     string        `yaml:"command"`
+}
+
+// UnmarshalYAML accepts both the boolean shorthand and the full mapping.
+func (n *NotifyConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if err := value.Decode(&n.Enabled); err != nil {
+			return err
+		}
+		n.Desktop = n.Enabled
+		return nil
+	}
+	type plain NotifyConfig
+	n.Enabled = true
+	if err := value.Decode((*plain)(n)); err != nil {
+		return err
+	}
+	if !n.Bell && n.Webhook == "" && n.Command == "" && !hasKey(value, "desktop") {
+		n.Desktop = true
+	}
+	return nil
+}
+
+// hasKey reports whether the mapping node sets key.
+func hasKey(mapping *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
+
+// FingerprintConfig represents what besides sources decides whether a runnable is up to date.
+type FingerprintConfig struct {
+	Env  bool `yaml:"env"`
+	Args bool `yaml:"args"`
+}
+
+// LimitsConfig represents the resource limits applied to the commands of a runnable.
+// Zero values leave the corresponding limit untouched.
+type LimitsConfig struct {
+	CPUSeconds   uint64 `yaml:"cpu_seconds"`
+	Memory       string `yaml:"memory"`
+	OpenFiles    uint64 `yaml:"open_files"`
+	MaxProcesses uint64 `yaml:"max_processes"`
+	Nice         int    `yaml:"nice"`
+	IONice       string `yaml:"ionice"`
+}
+
+// SandboxConfig represents the restrictions a runnable runs under.
+// It can be given as a boolean (`sandbox: true`) or as a mapping, which enables
+// the sandbox unless `enabled: false` is set.
+type SandboxConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Writable []string `yaml:"writable"`
+	// Network set to false denies TCP bind and connect. UDP and raw sockets
+	// are not restricted.
+	Network *bool `yaml:"network"`
+}
+
+// UnmarshalYAML accepts both the boolean shorthand and the full mapping.
+func (s *SandboxConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&s.Enabled)
+	}
+	type plain SandboxConfig
+	s.Enabled = true
+	return value.Decode((*plain)(s))
+}
+
+// ServiceConfig represents how a long-running runnable is supervised.
+type ServiceConfig struct {
+	// Restart is one of "no", "on-failure" or "always".
+	Restart      string        `yaml:"restart"`
+	Backoff      time.Duration `yaml:"backoff"`
+	MaxRestarts  int           `yaml:"max_restarts"`
+	Ready        string        `yaml:"ready"`
+	ReadyTimeout time.Duration `yaml:"ready_timeout"`
+}
+
+// WaitCondition represents something a runnable waits for before it runs.
+// Exactly one of TCP, File, HTTP and Command is set.
+type WaitCondition struct {
+	TCP      string        `yaml:"tcp"`
+	File     string        `yaml:"file"`
+	HTTP     string        `yaml:"http"`
+	Command  string        `yaml:"command"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// LogConfig represents the output capture settings for a runnable.
+// It can be given as a boolean (`log: true`) or as a mapping, which enables
+// logging unless `enabled: false` is set.
+type LogConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Dir     string        `yaml:"dir"`
+	Keep    int           `yaml:"keep"`
+	MaxAge  time.Duration `yaml:"max_age"`
+}
+
+// UnmarshalYAML accepts both the boolean shorthand and the full mapping.
+func (l *LogConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&l.Enabled)
+	}
+	type plain LogConfig
+	l.Enabled = true
+	return value.Decode((*plain)(l))
+}
+
+// ConfirmConfig represents the confirmation asked before a runnable starts.
+// It can be given as a boolean, as a custom message, or as a mapping.
+type ConfirmConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Message  string `yaml:"message"`
+	TypeName bool   `yaml:"type_name"`
+}
+
+// UnmarshalYAML accepts a boolean, a message string or the full mapping.
+func (c *ConfirmConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.ShortTag() == "!!bool" {
+			return value.Decode(&c.Enabled)
+		}
+		c.Enabled = true
+		return value.Decode(&c.Message)
+	}
+	type plain ConfirmConfig
+	c.Enabled = true
+	return value.Decode((*plain)(c))
+}
+
+// RequiresConfig represents what must be available before a runnable starts.
+type RequiresConfig struct {
+	Binaries []BinaryRequirement `yaml:"binaries,omitempty"`
+	Env      []EnvRequirement    `yaml:"env,omitempty"`
+	Files    []string            `yaml:"files,omitempty"`
+}
+
+// EnvRequirement represents an environment variable that must be set, optionally
+// asked for when missing. It can be given as a plain name or as a mapping.
+type EnvRequirement struct {
+	Name   string       `yaml:"name"`
+	Prompt PromptConfig `yaml:"prompt,omitempty"`
+}
+
+// UnmarshalYAML accepts a variable name or the full mapping.
+func (e *EnvRequirement) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&e.Name)
+	}
+	type plain EnvRequirement
+	return value.Decode((*plain)(e))
+}
+
+// PromptConfig represents how a missing value is asked for on a terminal.
+// It can be given as a boolean, as a custom message, or as a mapping.
+type PromptConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Message string   `yaml:"message,omitempty"`
+	Choices []string `yaml:"choices,omitempty"`
+	Default string   `yaml:"default,omitempty"`
+	// Secret reads the value without echoing it.
+	Secret bool `yaml:"secret,omitempty"`
+}
+
+// UnmarshalYAML accepts a boolean, a message string or the full mapping.
+func (p *PromptConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.ShortTag() == "!!bool" {
+			return value.Decode(&p.Enabled)
+		}
+		p.Enabled = true
+		return value.Decode(&p.Message)
+	}
+	type plain PromptConfig
+	p.Enabled = true
+	return value.Decode((*plain)(p))
+}
+
+// BinaryRequirement represents a binary expected on PATH, optionally with a minimum version.
+// It can be given as a plain name or as a mapping.
+type BinaryRequirement struct {
+	Name           string `yaml:"name"`
+	MinVersion     string `yaml:"min_version,omitempty"`
+	VersionCommand string `yaml:"version_command,omitempty"`
+	VersionRegex   string `yaml:"version_regex,omitempty"`
+}
+
+// UnmarshalYAML accepts a binary name or the full mapping.
+func (b *BinaryRequirement) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&b.Name)
+	}
+	type plain BinaryRequirement
+	return value.Decode((*plain)(b))
+}
+
 // LoadCollectionConfig loads the collection configuration from the given path.
 func LoadCollectionConfig(path string) (*CollectionConfig, error) {
 	data, err := os.ReadFile(filepath.Join(path, "collection.yml"))
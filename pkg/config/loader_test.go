@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadCollectionConfig(t *testing.T) {
@@ -41,6 +42,37 @@ environments:
 	}
 }
 
+func TestSaveCollectionConfig_OmitsUnsetFields(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &CollectionConfig{
+		Name:        "col",
+		Runnables:   []string{"a"},
+		EnvDefaults: map[string]string{},
+		PassEnv:     []string{},
+		Requires:    RequiresConfig{Binaries: []BinaryRequirement{}, Env: []EnvRequirement{{Name: "TOKEN"}}},
+	}
+	if err := SaveCollectionConfig(tempDir, cfg); err != nil {
+		t.Fatalf("SaveCollectionConfig failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "collection.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "name: col\nhelp: \"\"\nreadme: \"\"\nrunnables:\n    - a\nenvironments: {}\nrequires:\n    env:\n        - name: TOKEN\n"
+	if string(data) != want {
+		t.Errorf("expected %q, got %q", want, data)
+	}
+
+	loaded, err := LoadCollectionConfig(tempDir)
+	if err != nil {
+		t.Fatalf("LoadCollectionConfig failed: %v", err)
+	}
+	if len(loaded.Requires.Env) != 1 || loaded.Requires.Env[0].Name != "TOKEN" || loaded.Requires.Env[0].Prompt.Enabled {
+		t.Errorf("unexpected requirements after a round trip: %+v", loaded.Requires)
+	}
+}
+
 func TestLoadRunnableConfig(t *testing.T) {
 	tempDir := t.TempDir()
 	configFile := filepath.Join(tempDir, "runnable.yml")
@@ -66,3 +98,133 @@ environments:
 		t.Errorf("Environment variable mismatch")
 	}
 }
+
+func TestLoadRunnableConfig_Log(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "runnable.yml")
+
+	if err := os.WriteFile(configFile, []byte("run: echo\nlog: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := LoadRunnableConfig(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load runnable config: %v", err)
+	}
+	if !cfg.Log.Enabled {
+		t.Error("Expected boolean shorthand to enable logging")
+	}
+
+	content := `
+run: echo
+log:
+  dir: logs
+  keep: 3
+  max_age: 24h
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err = LoadRunnableConfig(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load runnable config: %v", err)
+	}
+	if !cfg.Log.Enabled || cfg.Log.Dir != "logs" || cfg.Log.Keep != 3 || cfg.Log.MaxAge != 24*time.Hour {
+		t.Errorf("Unexpected log config: %+v", cfg.Log)
+	}
+}
+
+func TestLoadRunnableConfig_Confirm(t *testing.T) {
+	tests := []struct {
+		content string
+		want    ConfirmConfig
+	}{
+		{"confirm: true", ConfirmConfig{Enabled: true}},
+		{"confirm: false", ConfirmConfig{}},
+		{`confirm: "Drop the database?"`, ConfirmConfig{Enabled: true, Message: "Drop the database?"}},
+		{"confirm:\n  message: Sure?\n  type_name: true", ConfirmConfig{Enabled: true, Message: "Sure?", TypeName: true}},
+	}
+
+	for _, tt := range tests {
+		tempDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tempDir, "runnable.yml"), []byte(tt.content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		cfg, err := LoadRunnableConfig(tempDir)
+		if err != nil {
+			t.Fatalf("Failed to load runnable config: %v", err)
+		}
+		if cfg.Confirm != tt.want {
+			t.Errorf("For %q expected %+v, got %+v", tt.content, tt.want, cfg.Confirm)
+		}
+	}
+}
+
+func TestLoadRunnableConfig_RequiresEnv(t *testing.T) {
+	content := `
+requires:
+  env:
+    - KUBECONFIG
+    - name: TARGET
+      prompt:
+        message: Target environment
+        choices: [staging, production]
+        default: staging
+    - name: TOKEN
+      prompt: true
+    - name: REGION
+      prompt: "Region?"
+`
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "runnable.yml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := LoadRunnableConfig(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load runnable config: %v", err)
+	}
+
+	env := cfg.Requires.Env
+	if len(env) != 4 {
+		t.Fatalf("Expected 4 env requirements, got %+v", env)
+	}
+	if env[0].Name != "KUBECONFIG" || env[0].Prompt.Enabled {
+		t.Errorf("Unexpected plain requirement: %+v", env[0])
+	}
+	target := env[1].Prompt
+	if env[1].Name != "TARGET" || !target.Enabled || target.Message != "Target environment" || len(target.Choices) != 2 || target.Default != "staging" {
+		t.Errorf("Unexpected prompt requirement: %+v", env[1])
+	}
+	if !env[2].Prompt.Enabled || env[2].Prompt.Message != "" {
+		t.Errorf("Unexpected boolean prompt: %+v", env[2])
+	}
+	if !env[3].Prompt.Enabled || env[3].Prompt.Message != "Region?" {
+		t.Errorf("Unexpected message prompt: %+v", env[3])
+	}
+}
+
+func TestLoadRunnableConfig_Notify(t *testing.T) {
+	tests := []struct {
+		content string
+		want    NotifyConfig
+	}{
+		{"notify: true", NotifyConfig{Enabled: true, Desktop: true}},
+		{"notify: false", NotifyConfig{}},
+		{"notify:\n  on: failure\n  min_duration: 30s\n  bell: true\n  webhook: http://localhost/hook", NotifyConfig{Enabled: true, On: "failure", MinDuration: 30 * time.Second, Bell: true, Webhook: "http://localhost/hook"}},
+		{"notify:\n  on: failure", NotifyConfig{Enabled: true, On: "failure", Desktop: true}},
+		{"notify:\n  on: failure\n  desktop: false", NotifyConfig{Enabled: true, On: "failure"}},
+	}
+
+	for _, tt := range tests {
+		tempDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tempDir, "runnable.yml"), []byte(tt.content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		cfg, err := LoadRunnableConfig(tempDir)
+		if err != nil {
+			t.Fatalf("Failed to load runnable config: %v", err)
+		}
+		if cfg.Notify != tt.want {
+			t.Errorf("For %q expected %+v, got %+v", tt.content, tt.want, cfg.Notify)
+		}
+	}
+}
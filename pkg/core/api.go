@@ -0,0 +1,148 @@
+package core
+
+import (
+	"context"
+	"io"
+	"maps"
+	"time"
+)
+
+// Phases reported in a Result.
+const (
+	PhaseBeforeEach = "before_each"
+	PhaseBefore     = "before"
+	PhaseWait       = "wait_for"
+	PhaseRun        = "run"
+	PhaseAfter      = "after"
+	PhaseAfterEach  = "after_each"
+)
+
+// Executor runs runnables for Go programs embedding shellican.
+// The zero value runs without stdin and discards all output.
+type Executor struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Env is added to the environment of the commands, over every other source.
+	Env map[string]string
+	// Dir is the working directory of the commands, the runnable directory when empty.
+	Dir string
+	// SkipHistory does not record the run in the run history.
+	SkipHistory bool
+	// Observer receives the events of the run, including its output.
+	Observer Observer
+	// Notifier also receives the notifications of runnables with notify
+	// enabled, when their settings ask for one.
+	Notifier Notifier
+
+	detachedID int
+}
+
+// PhaseResult is the outcome of a single phase of a run.
+type PhaseResult struct {
+	Phase    string        `json:"phase"`
+	Command  string        `json:"command,omitempty"`
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration"`
+	// Err is the failure of the phase. A failed after hook does not fail the run.
+	Err error `json:"-"`
+}
+
+// Result is the outcome of a run.
+type Result struct {
+	// ExitCode is 0 on success, the exit code of the failed command, 128+signal
+	// when it was killed, or -1 when the run failed before a command exited.
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration"`
+	// UpToDate is set when the run was skipped because its sources did not change.
+	UpToDate bool          `json:"up_to_date"`
+	Phases   []PhaseResult `json:"phases"`
+}
+
+// Phase returns the result of the given phase, or nil if it did not run.
+func (r *Result) Phase(phase string) *PhaseResult {
+	for i := range r.Phases {
+		if r.Phases[i].Phase == phase {
+			return &r.Phases[i]
+		}
+	}
+	return nil
+}
+
+// record appends the outcome of a phase that started at start. It is a no-op on a nil Result.
+func (r *Result) record(phase, command string, start time.Time, err error) {
+	if r == nil {
+		return
+	}
+	r.Phases = append(r.Phases, PhaseResult{
+		Phase:    phase,
+		Command:  command,
+		ExitCode: exitCode(err),
+		Duration: time.Since(start),
+		Err:      err,
+	})
+}
+
+// Run executes a runnable and returns its outcome. Cancelling c terminates the
+// command currently running. The returned error is the one the CLI reports;
+// the Result is returned even when it is not nil.
+func (e *Executor) Run(c context.Context, ctx *ExecutionContext, args []string) (*Result, error) {
+	if len(e.Env) > 0 {
+		extended := *ctx
+		extended.Environments = maps.Clone(ctx.Environments)
+		extended.EnvironmentSources = maps.Clone(ctx.EnvironmentSources)
+		if extended.Environments == nil {
+			extended.Environments = make(map[string]string)
+		}
+		if extended.EnvironmentSources == nil {
+			extended.EnvironmentSources = make(map[string]string)
+		}
+		for name, value := range e.Env {
+			extended.Environments[name] = value
+			extended.EnvironmentSources[name] = EnvSourceCLI
+		}
+		ctx = &extended
+	}
+
+	result := &Result{}
+	opts := runOptions{
+		stdin:      e.Stdin,
+		stdout:     e.Stdout,
+		stderr:     e.Stderr,
+		workDir:    e.Dir,
+		detachedID: e.detachedID,
+		result:     result,
+	}
+	if opts.stdout == nil {
+		opts.stdout = io.Discard
+	}
+	if opts.stderr == nil {
+		opts.stderr = io.Discard
+	}
+	if e.Observer != nil {
+		opts.events = &eventEmitter{observer: e.Observer, collection: ctx.Collection, runnable: ctx.Runnable}
+		opts.stdout = &outputWriter{w: opts.stdout, em: opts.events, stream: "stdout"}
+		opts.stderr = &outputWriter{w: opts.stderr, em: opts.events, stream: "stderr"}
+		opts.events.emit(Event{Type: EventResolved, Command: ctx.Config.Run})
+	}
+
+	start := time.Now()
+	err := execute(c, ctx, args, opts)
+	result.Duration = time.Since(start)
+	result.ExitCode = exitCode(err)
+	if !e.SkipHistory {
+		recordHistory(ctx, args, start, err, writerOrDiscard(e.Stdout))
+	}
+	if !result.UpToDate {
+		notifyRun(ctx, e.Notifier, result.Duration, err, writerOrDiscard(e.Stdout), writerOrDiscard(e.Stderr))
+	}
+	return result, err
+}
+
+// writerOrDiscard returns w, or io.Discard when it is nil.
+func writerOrDiscard(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestExecutor_Run(t *testing.T) {
+	runDir := t.TempDir()
+	workDir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	e := &Executor{
+		Stdin:       strings.NewReader("from stdin\n"),
+		Stdout:      &stdout,
+		Stderr:      &stderr,
+		Env:         map[string]string{"GREETING": "hello"},
+		Dir:         workDir,
+		SkipHistory: true,
+	}
+	ctx := &ExecutionContext{
+		RunnablePath: runDir,
+		Config: &config.RunnableConfig{
+			Before: "cat",
+			Run:    `echo "$GREETING $1"; pwd; echo oops >&2`,
+			After:  "exit 4",
+		},
+		Environments: map[string]string{"GREETING": "overridden"},
+	}
+
+	result, err := e.Run(context.Background(), ctx, []string{"world"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := "from stdin\nhello world\n" + workDir + "\n"
+	if !strings.HasPrefix(stdout.String(), want) {
+		t.Errorf("expected stdout to start with %q, got %q", want, stdout.String())
+	}
+	if stderr.String() != "oops\n" {
+		t.Errorf("expected stderr %q, got %q", "oops\n", stderr.String())
+	}
+	if ctx.Environments["GREETING"] != "overridden" {
+		t.Error("expected the context not to be modified")
+	}
+
+	if result.ExitCode != 0 || result.Duration <= 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(result.Phases) != 3 {
+		t.Fatalf("expected 3 phases, got %+v", result.Phases)
+	}
+	for i, phase := range []string{PhaseBefore, PhaseRun, PhaseAfter} {
+		if result.Phases[i].Phase != phase {
+			t.Errorf("expected phase %d to be %s, got %s", i, phase, result.Phases[i].Phase)
+		}
+	}
+	after := result.Phase(PhaseAfter)
+	if after.ExitCode != 4 || after.Err == nil {
+		t.Errorf("expected failed after hook with exit code 4, got %+v", after)
+	}
+	if result.Phase(PhaseWait) != nil {
+		t.Error("expected no wait_for phase")
+	}
+}
+
+func TestExecutor_RunFailure(t *testing.T) {
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config:       &config.RunnableConfig{Run: "exit 7", After: "echo after"},
+	}
+
+	result, err := (&Executor{SkipHistory: true}).Run(context.Background(), ctx, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if result.ExitCode != 7 || result.Phase(PhaseRun).ExitCode != 7 {
+		t.Errorf("expected exit code 7, got %+v", result)
+	}
+	if result.Phase(PhaseAfter) != nil {
+		t.Error("expected after hook not to run")
+	}
+}
+
+func TestExecutor_RunUpToDate(t *testing.T) {
+	t.Setenv("SHELLICAN_HOME", t.TempDir())
+	runDir := t.TempDir()
+	ctx := &ExecutionContext{
+		Collection:   "col1",
+		Runnable:     "run1",
+		RunnablePath: runDir,
+		Config:       &config.RunnableConfig{Run: "true", Sources: []string{"runnable.yml"}},
+	}
+	if err := os.WriteFile(filepath.Join(runDir, "runnable.yml"), []byte("run: true"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &Executor{SkipHistory: true}
+	if result, err := e.Run(context.Background(), ctx, nil); err != nil || result.UpToDate {
+		t.Fatalf("expected first run to execute, got %+v, %v", result, err)
+	}
+	result, err := e.Run(context.Background(), ctx, nil)
+	if err != nil || !result.UpToDate || len(result.Phases) != 0 {
+		t.Errorf("expected second run to be skipped, got %+v, %v", result, err)
+	}
+}
+
+func TestExecutor_RunWarningsGoToStdout(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("SHELLICAN_HOME", home)
+	// the state dir cannot be created, so history cannot be recorded
+	if err := os.MkdirAll(filepath.Join(home, ".shellican"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".shellican", ".state"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &ExecutionContext{
+		Collection:   "col",
+		Runnable:     "build",
+		RunnablePath: t.TempDir(),
+		Config:       &config.RunnableConfig{Run: "true"},
+	}
+	var stdout bytes.Buffer
+	if _, err := (&Executor{Stdout: &stdout}).Run(context.Background(), ctx, nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Warning: failed to record history") {
+		t.Errorf("expected the history warning on the executor stdout, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// confirmRun asks for the confirmation configured on a runnable.
+// It refuses to run when stdin is not a terminal unless AssumeYes is set.
+func confirmRun(ctx *ExecutionContext, opts runOptions) error {
+	c := ctx.Config.Confirm
+	if !c.Enabled || ctx.AssumeYes {
+		return nil
+	}
+
+	name := runnableName(ctx)
+	if !isTerminal(opts.stdin) {
+		return fmt.Errorf("runnable '%s' requires confirmation but stdin is not a terminal, use --yes to run it non-interactively", name)
+	}
+
+	ok, err := promptConfirmation(opts.stdin, opts.stderr, c, name)
+	if err != nil {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("aborted: runnable '%s' was not confirmed", name)
+	}
+	return nil
+}
+
+// promptConfirmation writes the confirmation prompt to w and reads the answer from r.
+func promptConfirmation(r io.Reader, w io.Writer, c config.ConfirmConfig, name string) (bool, error) {
+	message := c.Message
+	if message == "" {
+		message = fmt.Sprintf("Run '%s'?", name)
+	}
+
+	if c.TypeName {
+		_, _ = fmt.Fprintf(w, "%s\nType '%s' to continue: ", message, name)
+	} else {
+		_, _ = fmt.Fprintf(w, "%s [y/N]: ", message)
+	}
+
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.TrimSpace(answer)
+
+	if c.TypeName {
+		return answer == name, nil
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+// runnableName returns the name a runnable is invoked with.
+func runnableName(ctx *ExecutionContext) string {
+	if ctx.Runnable != "" {
+		return ctx.Runnable
+	}
+	return filepath.Base(ctx.RunnablePath)
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestPromptConfirmation(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.ConfirmConfig
+		answer string
+		want   bool
+	}{
+		{"yes", config.ConfirmConfig{Enabled: true}, "y\n", true},
+		{"yes long", config.ConfirmConfig{Enabled: true}, "YES\n", true},
+		{"default no", config.ConfirmConfig{Enabled: true}, "\n", false},
+		{"eof", config.ConfirmConfig{Enabled: true}, "", false},
+		{"typed name", config.ConfirmConfig{Enabled: true, TypeName: true}, "drop-db\n", true},
+		{"wrong name", config.ConfirmConfig{Enabled: true, TypeName: true}, "y\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := promptConfirmation(strings.NewReader(tt.answer), &out, tt.cfg, "drop-db")
+			if err != nil {
+				t.Fatalf("promptConfirmation failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	if _, err := promptConfirmation(strings.NewReader("n\n"), &out, config.ConfirmConfig{Enabled: true, Message: "Really?"}, "x"); err != nil {
+		t.Fatalf("promptConfirmation failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Really?") {
+		t.Errorf("Expected custom message, got %q", out.String())
+	}
+}
+
+func TestExecuteContext_Confirm(t *testing.T) {
+	tempDir := t.TempDir()
+
+	ctx := &ExecutionContext{
+		RunnablePath: tempDir,
+		Config: &config.RunnableConfig{
+			Before:  "touch before.out",
+			Run:     "true",
+			Confirm: config.ConfirmConfig{Enabled: true},
+		},
+		Environments: map[string]string{},
+	}
+
+	// stdin is not a terminal under go test
+	if err := ExecuteContext(ctx, nil); err == nil {
+		t.Fatal("Expected refusal without a terminal")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "before.out")); !os.IsNotExist(err) {
+		t.Error("Before hook must not run without confirmation")
+	}
+
+	ctx.AssumeYes = true
+	if err := ExecuteContext(ctx, nil); err != nil {
+		t.Errorf("ExecuteContext failed with AssumeYes: %v", err)
+	}
+}
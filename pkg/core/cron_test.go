@@ -0,0 +1,75 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	valid := []string{"* * * * *", "*/15 9-17 * * mon-fri", "0 0 1,15 * *", "30 2 * jan,jul sun", "@daily", "0 0 * * 7"}
+	for _, expr := range valid {
+		if _, err := parseCron(expr); err != nil {
+			t.Errorf("parseCron(%q) failed: %v", expr, err)
+		}
+	}
+
+	invalid := []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"}
+	for _, expr := range invalid {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) expected error", expr)
+		}
+	}
+}
+
+func TestCronSchedule_Matches(t *testing.T) {
+	// Monday
+	monday := time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"* * * * *", true},
+		{"*/15 9-17 * * mon-fri", true},
+		{"*/20 * * * *", false},
+		{"30 9 * * sun", false},
+		{"30 9 * * 1", true},
+		// restricted day-of-month and day-of-week are OR-ed
+		{"30 9 1 * mon", true},
+		{"30 9 19 * sun", true},
+		{"30 9 1 * sun", false},
+	}
+	for _, tt := range tests {
+		s, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q) failed: %v", tt.expr, err)
+		}
+		if got := s.Matches(monday); got != tt.want {
+			t.Errorf("%q.Matches(%s) = %v, want %v", tt.expr, monday, got, tt.want)
+		}
+	}
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	from := time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 19, 9, 31, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q) failed: %v", tt.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next() = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
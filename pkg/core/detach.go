@@ -0,0 +1,335 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// detachedRetention is how long records of exited background runs are kept.
+const detachedRetention = 24 * time.Hour
+
+// DetachedRun is a runnable started in the background with `run --detach`.
+type DetachedRun struct {
+	ID         int       `json:"id"`
+	Collection string    `json:"collection"`
+	Runnable   string    `json:"runnable"`
+	Args       []string  `json:"args"`
+	PID        int       `json:"pid"`
+	PGID       int       `json:"pgid"`
+	StartedAt  time.Time `json:"started_at"`
+	LogPath    string    `json:"log_path"`
+	Service    bool      `json:"service,omitempty"`
+}
+
+// Running reports whether the process of the run is still alive. A process
+// that is alive but no longer leads the recorded group is a reused PID.
+func (d *DetachedRun) Running() bool {
+	return leadsGroup(d.PID, d.PGID)
+}
+
+func getDetachedDir() (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "detached"), nil
+}
+
+// Detach starts command in the background, in its own session, with its
+// output going to a log file. command is expected to run the runnable of ctx
+// in the foreground; the confirmation of ctx is asked before detaching, since
+// the background process has no terminal.
+func Detach(ctx *ExecutionContext, args []string, command []string) (*DetachedRun, error) {
+	if os.Getenv(detachedIDEnv) != "" {
+		return nil, fmt.Errorf("already running in the background")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	if err := startSession(cmd); err != nil {
+		return nil, err
+	}
+	if err := confirmRun(ctx, defaultRunOptions()); err != nil {
+		return nil, err
+	}
+
+	dir, err := getDetachedDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state dir: %w", err)
+	}
+
+	lock, err := os.OpenFile(filepath.Join(dir, ".lock"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock: %w", err)
+	}
+	defer func() { _ = lock.Close() }()
+	unlock, err := lockFile(lock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock detached runs: %w", err)
+	}
+	defer unlock()
+
+	runs, err := loadDetached(dir)
+	if err != nil {
+		return nil, err
+	}
+	run := &DetachedRun{
+		ID:         1,
+		Collection: ctx.Collection,
+		Runnable:   ctx.Runnable,
+		Args:       args,
+		Service:    isService(ctx.Config),
+	}
+	for _, r := range runs {
+		run.ID = max(run.ID, r.ID+1)
+	}
+	run.LogPath = filepath.Join(dir, strconv.Itoa(run.ID)+".log")
+
+	logFile, err := os.OpenFile(run.LogPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log file: %w", err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.Env = append(os.Environ(), detachedIDEnv+"="+strconv.Itoa(run.ID))
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start background run: %w", err)
+	}
+	run.PID = cmd.Process.Pid
+	// a session leader also leads its process group
+	run.PGID = cmd.Process.Pid
+	run.StartedAt = time.Now()
+	// reap the process should it exit before this one does
+	go func() { _ = cmd.Wait() }()
+
+	if err := saveDetached(dir, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// DetachedCommand returns the command line running `run` in the foreground,
+// without confirmation prompts, from the flags parsed for the current
+// invocation and its positional args. The event stream is left out: the
+// background run does not inherit the descriptor it is written to.
+func DetachedCommand(exe string, flags *pflag.FlagSet, args []string) []string {
+	command := []string{exe, "run", "--yes"}
+	flags.Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "detach", "yes", "events", "events-fd":
+			return
+		}
+		if values, ok := f.Value.(pflag.SliceValue); ok {
+			for _, value := range values.GetSlice() {
+				command = append(command, "--"+f.Name+"="+value)
+			}
+			return
+		}
+		command = append(command, "--"+f.Name+"="+f.Value.String())
+	})
+	command = append(command, "--")
+	return append(command, args...)
+}
+
+func saveDetached(dir string, run *DetachedRun) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal detached run: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(run.ID)+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write detached run: %w", err)
+	}
+	return nil
+}
+
+// loadDetached reads the records in dir, ordered by ID.
+func loadDetached(dir string) ([]*DetachedRun, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list detached runs: %w", err)
+	}
+
+	var runs []*DetachedRun
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read detached run: %w", err)
+		}
+		var run DetachedRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			// skip records left half-written
+			continue
+		}
+		runs = append(runs, &run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID < runs[j].ID })
+	return runs, nil
+}
+
+// removeDetached deletes the record of run along with its log.
+func removeDetached(dir string, run *DetachedRun) error {
+	if err := os.Remove(filepath.Join(dir, strconv.Itoa(run.ID)+".json")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove detached run: %w", err)
+	}
+	if err := os.Remove(run.LogPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove log: %w", err)
+	}
+	if err := os.Remove(filepath.Join(dir, strconv.Itoa(run.ID)+".status")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove service status: %w", err)
+	}
+	return nil
+}
+
+// ListDetached returns the background runs, removing the records of runs that
+// exited more than detachedRetention ago, or of every exited run with clean.
+func ListDetached(clean bool) ([]*DetachedRun, error) {
+	dir, err := getDetachedDir()
+	if err != nil {
+		return nil, err
+	}
+	runs, err := loadDetached(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []*DetachedRun
+	for _, run := range runs {
+		if !run.Running() {
+			stale := clean
+			// the log is written until the run exits
+			if info, err := os.Stat(run.LogPath); err != nil || time.Since(info.ModTime()) > detachedRetention {
+				stale = true
+			}
+			if stale {
+				if err := removeDetached(dir, run); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		kept = append(kept, run)
+	}
+	return kept, nil
+}
+
+// FindDetached returns the background run with the given ID.
+func FindDetached(id int) (*DetachedRun, error) {
+	dir, err := getDetachedDir()
+	if err != nil {
+		return nil, err
+	}
+	runs, err := loadDetached(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("no background run with id %d", id)
+}
+
+// ShowDetached prints the background runs.
+func ShowDetached(clean bool) error {
+	runs, err := ListDetached(clean)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Println("No background runs found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tCOLLECTION\tRUNNABLE\tPID\tSTARTED\tSTATUS")
+	for _, run := range runs {
+		status := detachedStatus(run)
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\n",
+			run.ID, run.Collection, run.Runnable, run.PID, run.StartedAt.Format(time.DateTime), status)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	return nil
+}
+
+// detachedStatus describes the state of a background run, including the
+// supervisor state of services.
+func detachedStatus(run *DetachedRun) string {
+	if !run.Running() {
+		return "exited"
+	}
+	if !run.Service {
+		return "running"
+	}
+	status, err := loadServiceStatus(run.ID)
+	if err != nil {
+		return "running"
+	}
+	if status.Restarts > 0 {
+		return fmt.Sprintf("%s, %d restarts", status.State, status.Restarts)
+	}
+	return status.State
+}
+
+// StopDetached terminates a background run, killing its process group if it
+// is still around after a grace period. Services are asked to stop through
+// their supervisor only, so that they can run their after hook.
+func StopDetached(id int) error {
+	run, err := FindDetached(id)
+	if err != nil {
+		return err
+	}
+	if !run.Running() {
+		return fmt.Errorf("background run %d is not running", id)
+	}
+
+	target, grace := -run.PGID, killGracePeriod
+	if run.Service {
+		target, grace = run.PID, serviceStopTimeout
+	}
+	if err := terminateProcess(target); err != nil {
+		return fmt.Errorf("failed to stop background run: %w", err)
+	}
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		if !run.Running() {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err := killProcess(-run.PGID); err != nil {
+		return fmt.Errorf("failed to kill background run: %w", err)
+	}
+	return nil
+}
+
+// ShowDetachedLogs prints the output of a background run. Following stops
+// once the run has exited.
+func ShowDetachedLogs(id int, follow bool) error {
+	run, err := FindDetached(id)
+	if err != nil {
+		return err
+	}
+	return printLog(os.Stdout, run.LogPath, follow, run.Running)
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+	"github.com/spf13/pflag"
+)
+
+func TestDetach_PsAndStop(t *testing.T) {
+	t.Setenv("SHELLICAN_HOME", t.TempDir())
+
+	ctx := &ExecutionContext{Collection: "col1", Runnable: "run1", Config: &config.RunnableConfig{}}
+	run, err := Detach(ctx, []string{"a"}, []string{"/bin/sh", "-c", "echo started; exec sleep 30"})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if run.ID != 1 || run.PID == 0 || run.PGID != run.PID {
+		t.Errorf("unexpected run: %+v", run)
+	}
+
+	waitFor(t, "background output", func() bool {
+		data, _ := os.ReadFile(run.LogPath)
+		return strings.Contains(string(data), "started")
+	})
+
+	runs, err := ListDetached(false)
+	if err != nil {
+		t.Fatalf("ListDetached failed: %v", err)
+	}
+	if len(runs) != 1 || !runs[0].Running() || runs[0].Collection != "col1" {
+		t.Fatalf("expected one running run, got %+v", runs)
+	}
+
+	second, err := Detach(ctx, nil, []string{"/bin/sh", "-c", "exit 0"})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if second.ID != 2 {
+		t.Errorf("expected sequential id 2, got %d", second.ID)
+	}
+
+	if err := StopDetached(run.ID); err != nil {
+		t.Fatalf("StopDetached failed: %v", err)
+	}
+	if run.Running() {
+		t.Error("expected run to be stopped")
+	}
+	if err := StopDetached(run.ID); err == nil {
+		t.Error("expected error when stopping a run that is not running")
+	}
+
+	waitFor(t, "second run to exit", func() bool { return !second.Running() })
+
+	// exited runs are listed until cleaned
+	if runs, _ = ListDetached(false); len(runs) != 2 {
+		t.Fatalf("expected exited runs to be kept, got %d", len(runs))
+	}
+	if runs, _ = ListDetached(true); len(runs) != 0 {
+		t.Fatalf("expected exited runs to be cleaned, got %d", len(runs))
+	}
+	if _, err := os.Stat(run.LogPath); !os.IsNotExist(err) {
+		t.Errorf("expected log to be removed with its run, got %v", err)
+	}
+	if _, err := FindDetached(run.ID); err == nil {
+		t.Error("expected cleaned run to be gone")
+	}
+}
+
+func TestPrintLog_FollowStopsWhenRunExits(t *testing.T) {
+	t.Setenv("SHELLICAN_HOME", t.TempDir())
+
+	ctx := &ExecutionContext{Collection: "col1", Runnable: "run1", Config: &config.RunnableConfig{}}
+	run, err := Detach(ctx, nil, []string{"/bin/sh", "-c", "echo first; sleep 1; echo last"})
+	if err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() { done <- printLog(&out, run.LogPath, true, run.Running) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("printLog failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected following to stop once the run exited")
+	}
+	if out.String() != "first\nlast\n" {
+		t.Errorf("expected the whole log to be printed, got %q", out.String())
+	}
+}
+
+func TestDetachedCommand(t *testing.T) {
+	for _, argv := range [][]string{
+		{"-yd", "-e", "A=1", "-eB=x,y", "--force", "col", "x", "--", "-d", "--detach"},
+		{"--detach", "col", "-e", "A=1", "x", "-e", "B=x,y", "--force", "--events", "jsonl", "--events-fd=4", "--", "-d", "--detach"},
+	} {
+		flags := pflag.NewFlagSet("run", pflag.ContinueOnError)
+		flags.BoolP("detach", "d", false, "")
+		flags.BoolP("yes", "y", false, "")
+		flags.Bool("force", false, "")
+		flags.StringArrayP("env", "e", nil, "")
+		flags.IntP("jobs", "j", 1, "")
+		flags.String("events", "", "")
+		flags.Int("events-fd", 3, "")
+		if err := flags.Parse(argv); err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		command := DetachedCommand("/bin/shellican", flags, flags.Args())
+		want := []string{"/bin/shellican", "run", "--yes", "--env=A=1", "--env=B=x,y", "--force=true", "--", "col", "x", "-d", "--detach"}
+		if !slices.Equal(command, want) {
+			t.Errorf("DetachedCommand(%q) = %q, want %q", argv, command, want)
+		}
+	}
+}
+
+func TestDetach_AlreadyDetached(t *testing.T) {
+	t.Setenv("SHELLICAN_HOME", t.TempDir())
+	t.Setenv(detachedIDEnv, "1")
+
+	ctx := &ExecutionContext{Collection: "col1", Runnable: "run1", Config: &config.RunnableConfig{}}
+	if _, err := Detach(ctx, nil, []string{"/bin/sh", "-c", "exit 0"}); err == nil || !strings.Contains(err.Error(), "already running in the background") {
+		t.Errorf("expected a background run not to detach, got %v", err)
+	}
+}
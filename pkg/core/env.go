@@ -0,0 +1,131 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultPassEnv lists the process variables kept even when the environment
+// is not inherited, so that commands can still be found and run.
+var defaultPassEnv = []string{"HOME", "LOGNAME", "PATH", "SHELL", "TERM", "USER"}
+
+// resolveEnvironment returns the environment the commands of ctx start with,
+// sorted by name, along with where each variable comes from.
+func resolveEnvironment(ctx *ExecutionContext) []PlanVariable {
+	merged := make(map[string]PlanVariable)
+	for name, value := range ctx.EnvDefaults {
+		merged[name] = PlanVariable{Name: name, Value: value, Source: EnvSourceDefault}
+	}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if inheritsVariable(ctx, name) {
+			merged[name] = PlanVariable{Name: name, Value: value, Source: EnvSourceOS}
+		}
+	}
+	for name, value := range ctx.Environments {
+		source := ctx.EnvironmentSources[name]
+		if source == "" {
+			source = EnvSourceRunnable
+		}
+		merged[name] = PlanVariable{Name: name, Value: value, Source: source}
+	}
+
+	vars := make([]PlanVariable, 0, len(merged))
+	for _, v := range merged {
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// inheritsVariable reports whether a process variable is passed to the commands of ctx.
+func inheritsVariable(ctx *ExecutionContext, name string) bool {
+	if matchEnvPattern(ctx.UnsetEnv, name) {
+		return false
+	}
+	if !ctx.CleanEnv {
+		return true
+	}
+	return matchEnvPattern(defaultPassEnv, name) || matchEnvPattern(ctx.PassEnv, name)
+}
+
+// matchEnvPattern reports whether name matches one of patterns, which may use
+// shell wildcards such as AWS_*.
+func matchEnvPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// buildEnv returns the environment of the commands of ctx in os/exec form.
+func buildEnv(ctx *ExecutionContext) []string {
+	vars := resolveEnvironment(ctx)
+	env := make([]string, 0, len(vars))
+	for _, v := range vars {
+		env = append(env, v.Name+"="+v.Value)
+	}
+	return env
+}
+
+// envMap returns the environment of the commands of ctx as a map.
+func envMap(ctx *ExecutionContext) map[string]string {
+	vars := resolveEnvironment(ctx)
+	env := make(map[string]string, len(vars))
+	for _, v := range vars {
+		env[v.Name] = v.Value
+	}
+	return env
+}
+
+// ParseEnvOverrides builds the command line environment overrides from env files
+// and KEY=VALUE assignments. Assignments win over env files, later files win
+// over earlier ones.
+func ParseEnvOverrides(assignments []string, envFiles []string) (map[string]string, error) {
+	overrides := make(map[string]string)
+
+	for _, file := range envFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read env file: %w", err)
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			line = strings.TrimPrefix(line, "export ")
+			name, value, ok := strings.Cut(line, "=")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", file, i+1)
+			}
+			overrides[name] = unquote(strings.TrimSpace(value))
+		}
+	}
+
+	for _, assignment := range assignments {
+		name, value, ok := strings.Cut(assignment, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid environment override '%s': expected KEY=VALUE", assignment)
+		}
+		overrides[name] = value
+	}
+
+	return overrides, nil
+}
+
+// unquote removes matching single or double quotes around an env file value.
+func unquote(value string) string {
+	if len(value) >= 2 {
+		if (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
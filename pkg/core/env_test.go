@@ -0,0 +1,191 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestResolveEnvironment(t *testing.T) {
+	t.Setenv("SHELLICAN_ENV_KEEP", "keep")
+	t.Setenv("AWS_PROFILE", "dev")
+	t.Setenv("AWS_SECRET", "secret")
+	t.Setenv("SHELLICAN_ENV_LEAK", "leak")
+
+	ctx := &ExecutionContext{
+		Environments: map[string]string{"DECLARED": "v"},
+		CleanEnv:     true,
+		PassEnv:      []string{"AWS_*"},
+		UnsetEnv:     []string{"AWS_SECRET"},
+	}
+
+	env := envMap(ctx)
+	if env["DECLARED"] != "v" || env["AWS_PROFILE"] != "dev" {
+		t.Errorf("Expected declared and passed variables, got %v", env)
+	}
+	if _, ok := env["PATH"]; !ok {
+		t.Error("Expected PATH to be kept in a clean environment")
+	}
+	for _, name := range []string{"SHELLICAN_ENV_LEAK", "AWS_SECRET"} {
+		if _, ok := env[name]; ok {
+			t.Errorf("Expected %s to be dropped", name)
+		}
+	}
+
+	ctx.CleanEnv = false
+	env = envMap(ctx)
+	if env["SHELLICAN_ENV_KEEP"] != "keep" {
+		t.Error("Expected process environment to be inherited")
+	}
+	if _, ok := env["AWS_SECRET"]; ok {
+		t.Error("Expected unset_env to apply when inheriting")
+	}
+}
+
+func TestExecuteContext_CleanEnv(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_ENV_LEAK", "leak")
+
+	ctx := &ExecutionContext{
+		RunnablePath: tempDir,
+		Config: &config.RunnableConfig{
+			Run: "env > env.out",
+		},
+		Environments: map[string]string{"DECLARED": "v"},
+		CleanEnv:     true,
+	}
+	if err := ExecuteContext(ctx, nil); err != nil {
+		t.Fatalf("ExecuteContext failed: %v", err)
+	}
+
+	out, err := os.ReadFile(filepath.Join(tempDir, "env.out"))
+	if err != nil {
+		t.Fatalf("Failed to read env.out: %v", err)
+	}
+	if strings.Contains(string(out), "SHELLICAN_ENV_LEAK") {
+		t.Error("Process environment leaked into a clean run")
+	}
+	if !strings.Contains(string(out), "DECLARED=v") {
+		t.Error("Declared environment missing from a clean run")
+	}
+}
+
+func TestResolveCommand_InheritEnv(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_HOME", tempDir)
+
+	if err := CreateCollection("col1"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	colPath := filepath.Join(tempDir, ".shellican", "col1")
+	colContent := "runnables: [strict, loose]\ninherit_env: false\npass_env: [\"AWS_*\"]\n"
+	if err := os.WriteFile(filepath.Join(colPath, "collection.yml"), []byte(colContent), 0644); err != nil {
+		t.Fatalf("failed to write collection config: %v", err)
+	}
+	for name, content := range map[string]string{
+		"strict": "run: env\npass_env: [GOPATH]",
+		"loose":  "run: env\ninherit_env: true",
+	} {
+		if err := os.MkdirAll(filepath.Join(colPath, name), 0755); err != nil {
+			t.Fatalf("failed to create runnable dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(colPath, name, "runnable.yml"), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write runnable config: %v", err)
+		}
+	}
+
+	strict, err := ResolveCommand("col1", []string{"strict"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	if !strict.CleanEnv || len(strict.PassEnv) != 2 {
+		t.Errorf("Expected collection policy with merged pass_env, got %v %v", strict.CleanEnv, strict.PassEnv)
+	}
+
+	loose, err := ResolveCommand("col1", []string{"loose"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	if loose.CleanEnv {
+		t.Error("Expected runnable inherit_env to override the collection")
+	}
+}
+
+func TestParseEnvOverrides(t *testing.T) {
+	tempDir := t.TempDir()
+	envFile := filepath.Join(tempDir, ".env")
+	content := "# comment\nexport A=file\nB=\"quoted value\"\nC='single'\n\n"
+	if err := os.WriteFile(envFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	overrides, err := ParseEnvOverrides([]string{"A=cli", "D=x=y"}, []string{envFile})
+	if err != nil {
+		t.Fatalf("ParseEnvOverrides failed: %v", err)
+	}
+	want := map[string]string{"A": "cli", "B": "quoted value", "C": "single", "D": "x=y"}
+	for k, v := range want {
+		if overrides[k] != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, overrides[k])
+		}
+	}
+
+	if _, err := ParseEnvOverrides([]string{"NOVALUE"}, nil); err == nil {
+		t.Error("Expected error for assignment without '='")
+	}
+	if _, err := ParseEnvOverrides(nil, []string{filepath.Join(tempDir, "missing")}); err == nil {
+		t.Error("Expected error for missing env file")
+	}
+}
+
+func TestResolveCommandWithEnv_Precedence(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_HOME", tempDir)
+	t.Setenv("FROM_OS", "os")
+	t.Setenv("DECLARED", "os")
+
+	if err := CreateCollection("col1"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	colPath := filepath.Join(tempDir, ".shellican", "col1")
+	colContent := "runnables: [run1]\nenvironments:\n  DECLARED: collection\n  COL_ONLY: collection\nenv_defaults:\n  FROM_OS: default\n"
+	if err := os.WriteFile(filepath.Join(colPath, "collection.yml"), []byte(colContent), 0644); err != nil {
+		t.Fatalf("failed to write collection config: %v", err)
+	}
+	runContent := "run: env\nrequires:\n  env: [REQUIRED]\nenvironments:\n  DECLARED: runnable\nenv_defaults:\n  UNSET_DEFAULT: default\n"
+	if err := os.MkdirAll(filepath.Join(colPath, "run1"), 0755); err != nil {
+		t.Fatalf("failed to create runnable dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(colPath, "run1", "runnable.yml"), []byte(runContent), 0644); err != nil {
+		t.Fatalf("failed to write runnable config: %v", err)
+	}
+
+	if _, err := ResolveCommand("col1", []string{"run1"}); err == nil {
+		t.Error("Expected missing requirement without override")
+	}
+
+	ctx, err := ResolveCommandWithEnv("col1", []string{"run1"}, map[string]string{"REQUIRED": "cli", "COL_ONLY": "cli"})
+	if err != nil {
+		t.Fatalf("ResolveCommandWithEnv failed: %v", err)
+	}
+
+	sources := make(map[string]PlanVariable)
+	for _, v := range resolveEnvironment(ctx) {
+		sources[v.Name] = v
+	}
+	want := map[string]PlanVariable{
+		"FROM_OS":       {Value: "os", Source: EnvSourceOS},
+		"UNSET_DEFAULT": {Value: "default", Source: EnvSourceDefault},
+		"DECLARED":      {Value: "runnable", Source: EnvSourceRunnable},
+		"COL_ONLY":      {Value: "cli", Source: EnvSourceCLI},
+		"REQUIRED":      {Value: "cli", Source: EnvSourceCLI},
+	}
+	for name, w := range want {
+		if got := sources[name]; got.Value != w.Value || got.Source != w.Source {
+			t.Errorf("%s: expected %s from %s, got %s from %s", name, w.Value, w.Source, got.Value, got.Source)
+		}
+	}
+}
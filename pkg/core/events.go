@@ -0,0 +1,127 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types emitted to an Observer.
+const (
+	EventResolved     = "resolved"
+	EventHookStarted  = "hook_started"
+	EventHookFinished = "hook_finished"
+	EventRunStarted   = "run_started"
+	EventRunFinished  = "run_finished"
+	EventOutput       = "output"
+	EventRetry        = "retry"
+	EventTimeout      = "timeout"
+)
+
+// Event describes something that happened during a run.
+type Event struct {
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	Collection string    `json:"collection,omitempty"`
+	Runnable   string    `json:"runnable,omitempty"`
+	Phase      string    `json:"phase,omitempty"`
+	Command    string    `json:"command,omitempty"`
+	// Stream is "stdout" or "stderr" for output events, whose chunk is in Data.
+	Stream     string `json:"stream,omitempty"`
+	Data       string `json:"data,omitempty"`
+	ExitCode   *int   `json:"exit_code,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// Attempt is the number of the upcoming start of a restarted service.
+	Attempt int `json:"attempt,omitempty"`
+}
+
+// Observer receives the events of a run. Output events may be delivered from
+// several goroutines at once.
+type Observer interface {
+	Observe(Event)
+}
+
+// ObserverFunc adapts a function to the Observer interface.
+type ObserverFunc func(Event)
+
+// Observe calls f(e).
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+// jsonLinesObserver writes events as JSON lines.
+type jsonLinesObserver struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLinesObserver returns an Observer writing one JSON object per event to w.
+func NewJSONLinesObserver(w io.Writer) Observer {
+	return &jsonLinesObserver{enc: json.NewEncoder(w)}
+}
+
+func (o *jsonLinesObserver) Observe(e Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_ = o.enc.Encode(e)
+}
+
+// eventEmitter stamps events with the runnable they belong to.
+type eventEmitter struct {
+	observer   Observer
+	collection string
+	runnable   string
+}
+
+// emit sends e to the observer. It is a no-op on a nil emitter.
+func (em *eventEmitter) emit(e Event) {
+	if em == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.Collection = em.collection
+	e.Runnable = em.runnable
+	em.observer.Observe(e)
+}
+
+// outputWriter reports everything written through it as output events.
+type outputWriter struct {
+	w      io.Writer
+	em     *eventEmitter
+	stream string
+}
+
+func (o *outputWriter) Write(p []byte) (int, error) {
+	o.em.emit(Event{Type: EventOutput, Stream: o.stream, Data: string(p)})
+	return o.w.Write(p)
+}
+
+// phaseEvents returns the event types marking the start and end of phase.
+func phaseEvents(phase string) (string, string) {
+	if phase == PhaseRun {
+		return EventRunStarted, EventRunFinished
+	}
+	return EventHookStarted, EventHookFinished
+}
+
+// startPhase reports that phase is starting.
+func (opts runOptions) startPhase(phase, command string) time.Time {
+	started, _ := phaseEvents(phase)
+	opts.events.emit(Event{Type: started, Phase: phase, Command: command})
+	return time.Now()
+}
+
+// finishPhase records and reports the outcome of a phase that started at start.
+func (opts runOptions) finishPhase(phase, command string, start time.Time, err error) {
+	opts.result.record(phase, command, start, err)
+
+	_, finished := phaseEvents(phase)
+	code := exitCode(err)
+	e := Event{Type: finished, Phase: phase, Command: command, ExitCode: &code, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	opts.events.emit(e)
+}
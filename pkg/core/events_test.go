@@ -0,0 +1,140 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) Observe(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// types returns the recorded event types, without output events.
+func (r *eventRecorder) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var types []string
+	for _, e := range r.events {
+		if e.Type != EventOutput {
+			types = append(types, e.Type)
+		}
+	}
+	return types
+}
+
+func TestExecutor_Events(t *testing.T) {
+	rec := &eventRecorder{}
+	ctx := &ExecutionContext{
+		Collection:   "col1",
+		Runnable:     "run1",
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Before: "true",
+			Run:    "echo out; echo err >&2; exit 2",
+		},
+	}
+
+	var stdout bytes.Buffer
+	e := &Executor{Stdout: &stdout, Observer: rec, SkipHistory: true}
+	if _, err := e.Run(context.Background(), ctx, nil); err == nil {
+		t.Fatal("expected error")
+	}
+
+	want := []string{EventResolved, EventHookStarted, EventHookFinished, EventRunStarted, EventRunFinished}
+	if got := rec.types(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected events %v, got %v", want, got)
+	}
+	if stdout.String() != "out\n" {
+		t.Errorf("expected output to still reach stdout, got %q", stdout.String())
+	}
+
+	var output []string
+	for _, ev := range rec.events {
+		if ev.Collection != "col1" || ev.Runnable != "run1" || ev.Time.IsZero() {
+			t.Errorf("expected event to be stamped, got %+v", ev)
+		}
+		switch ev.Type {
+		case EventOutput:
+			output = append(output, ev.Stream+":"+ev.Data)
+		case EventRunFinished:
+			if ev.ExitCode == nil || *ev.ExitCode != 2 || ev.Error == "" {
+				t.Errorf("expected failed run event, got %+v", ev)
+			}
+		case EventHookFinished:
+			if ev.Phase != PhaseBefore || ev.ExitCode == nil || *ev.ExitCode != 0 {
+				t.Errorf("unexpected hook event %+v", ev)
+			}
+		}
+	}
+	joined := strings.Join(output, "")
+	if !strings.Contains(joined, "stdout:out\n") || !strings.Contains(joined, "stderr:err\n") {
+		t.Errorf("expected output events for both streams, got %q", joined)
+	}
+}
+
+func TestExecutor_RetryAndTimeoutEvents(t *testing.T) {
+	rec := &eventRecorder{}
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Run:     "exit 1",
+			Service: config.ServiceConfig{Restart: "on-failure", Backoff: time.Millisecond, MaxRestarts: 1},
+		},
+	}
+	e := &Executor{Observer: rec, SkipHistory: true}
+	if _, err := e.Run(context.Background(), ctx, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := strings.Join(rec.types(), ","); !strings.Contains(got, EventRetry) {
+		t.Errorf("expected a retry event, got %s", got)
+	}
+
+	rec = &eventRecorder{}
+	ctx.Config = &config.RunnableConfig{
+		Run:     "true",
+		WaitFor: []config.WaitCondition{{File: "missing", Timeout: time.Millisecond, Interval: time.Millisecond}},
+	}
+	e.Observer = rec
+	if _, err := e.Run(context.Background(), ctx, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := rec.types(); got[len(got)-1] != EventTimeout {
+		t.Errorf("expected a timeout event last, got %v", got)
+	}
+}
+
+func TestJSONLinesObserver(t *testing.T) {
+	var buf bytes.Buffer
+	obs := NewJSONLinesObserver(&buf)
+	code := 0
+	obs.Observe(Event{Type: EventRunFinished, Phase: PhaseRun, ExitCode: &code})
+	obs.Observe(Event{Type: EventOutput, Stream: "stdout", Data: "hi\n"})
+
+	scanner := bufio.NewScanner(&buf)
+	var events []Event
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 2 || events[0].ExitCode == nil || *events[0].ExitCode != 0 || events[1].Data != "hi\n" {
+		t.Errorf("unexpected events %+v", events)
+	}
+}
@@ -1,25 +1,92 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"maps"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/brsyuksel/shellican/pkg/config"
 )
 
 // ExecutionContext holds the execution state.
 type ExecutionContext struct {
+	Collection   string
+	Runnable     string
 	RunnablePath string
 	Config       *config.RunnableConfig
 	Environments map[string]string
+	// EnvironmentSources records where each entry of Environments was declared.
+	EnvironmentSources map[string]string
+	// EnvDefaults are only used when the process environment does not provide the variable.
+	EnvDefaults map[string]string
+	// AssumeYes skips the confirmation configured on the runnable.
+	AssumeYes bool
+	// Force runs the runnable even if its sources are unchanged.
+	Force bool
+	// Sandbox sandboxes the run even if the runnable does not ask for it.
+	Sandbox bool
+	// CleanEnv starts commands without the process environment, except for
+	// the variables allowed by PassEnv.
+	CleanEnv bool
+	// PassEnv lists the process variables kept when CleanEnv is set.
+	PassEnv []string
+	// UnsetEnv lists process variables that are never passed to commands.
+	UnsetEnv []string
+	// Prompts lists the required variables asked for when they are missing.
+	Prompts []config.EnvRequirement
+	// CollectionRequires are the requirements of the collection, checked
+	// along with those of the runnable.
+	CollectionRequires config.RequiresConfig
+	// EnvAssignments and EnvFiles are the command line arguments the
+	// environment overrides were parsed from, recorded in history for reruns.
+	EnvAssignments []string
+	EnvFiles       []string
+	// BeforeEach and AfterEach are the collection hooks wrapped around those
+	// of the runnable. They run in CollectionPath.
+	BeforeEach     string
+	AfterEach      string
+	CollectionPath string
 }
 
+// Environment sources reported by ExplainContext, from lowest to highest precedence.
+const (
+	EnvSourceDefault    = "default"
+	EnvSourceOS         = "os"
+	EnvSourceCollection = "collection"
+	EnvSourceRunnable   = "runnable"
+	EnvSourceCLI        = "cli"
+	EnvSourcePrompt     = "prompt"
+	EnvSourceMatrix     = "matrix"
+)
+
 // ResolveCommand resolves a runnable from a collection.
 func ResolveCommand(collection string, pathComponents []string) (*ExecutionContext, error) {
+	return ResolveCommandWithEnv(collection, pathComponents, nil)
+}
+
+// ResolveCommandWithEnv resolves a runnable from a collection, with environment
+// overrides given on the command line taking precedence over every other source.
+func ResolveCommandWithEnv(collection string, pathComponents []string, overrides map[string]string) (*ExecutionContext, error) {
+	return resolveCommand(collection, pathComponents, overrides, true)
+}
+
+// ResolveCommandUnchecked is ResolveCommandWithEnv without checking the
+// requirements, for callers checking them with CheckRequirements once the
+// command line settings are applied, or reporting them with ExplainContext.
+func ResolveCommandUnchecked(collection string, pathComponents []string, overrides map[string]string) (*ExecutionContext, error) {
+	return resolveCommand(collection, pathComponents, overrides, false)
+}
+
+func resolveCommand(collection string, pathComponents []string, overrides map[string]string, checkRequires bool) (*ExecutionContext, error) {
 	rootDir, err := getRoot()
 	if err != nil {
 		return nil, err
@@ -68,17 +135,68 @@ func ResolveCommand(collection string, pathComponents []string) (*ExecutionConte
 			return nil, fmt.Errorf("failed to load runnable config: %w", err)
 		}
 		if runCfg != nil {
+			if err := checkPlatform(runCfg, runName, currentPlatform); err != nil {
+				return nil, err
+			}
+			runCfg = applyOverrides(runCfg, currentPlatform)
+
 			mergedEnvs := make(map[string]string)
+			sources := make(map[string]string)
 
 			maps.Copy(mergedEnvs, colCfg.Environments)
+			for k := range colCfg.Environments {
+				sources[k] = EnvSourceCollection
+			}
 
 			maps.Copy(mergedEnvs, runCfg.Environments)
-
-			return &ExecutionContext{
-				RunnablePath: currentPath,
-				Config:       runCfg,
-				Environments: mergedEnvs,
-			}, nil
+			for k := range runCfg.Environments {
+				sources[k] = EnvSourceRunnable
+			}
+
+			maps.Copy(mergedEnvs, overrides)
+			for k := range overrides {
+				sources[k] = EnvSourceCLI
+			}
+
+			defaults := make(map[string]string)
+			maps.Copy(defaults, colCfg.EnvDefaults)
+			maps.Copy(defaults, runCfg.EnvDefaults)
+
+			cleanEnv := colCfg.InheritEnv != nil && !*colCfg.InheritEnv
+			if runCfg.InheritEnv != nil {
+				cleanEnv = !*runCfg.InheritEnv
+			}
+
+			ctx := &ExecutionContext{
+				Collection:         collection,
+				Runnable:           runName,
+				RunnablePath:       currentPath,
+				Config:             runCfg,
+				Environments:       mergedEnvs,
+				EnvironmentSources: sources,
+				EnvDefaults:        defaults,
+				CleanEnv:           cleanEnv,
+				PassEnv:            append(slices.Clone(colCfg.PassEnv), runCfg.PassEnv...),
+				UnsetEnv:           append(slices.Clone(colCfg.UnsetEnv), runCfg.UnsetEnv...),
+				Prompts:            promptedEnv(colCfg.Requires, runCfg.Requires),
+				CollectionRequires: colCfg.Requires,
+				CollectionPath:     rootDir,
+			}
+			if !runCfg.SkipCollectionHooks {
+				ctx.BeforeEach = colCfg.BeforeEach
+				ctx.AfterEach = colCfg.AfterEach
+			}
+
+			if checkRequires {
+				if err := CheckRequirements(ctx, nil); err != nil {
+					return nil, err
+				}
+			}
+			if err := checkTemplates(runCfg); err != nil {
+				return nil, err
+			}
+
+			return ctx, nil
 		}
 		return nil, fmt.Errorf("directory found but no runnable.yml: %s", currentPath)
 	}
@@ -86,72 +204,356 @@ func ResolveCommand(collection string, pathComponents []string) (*ExecutionConte
 	return nil, fmt.Errorf("target is a file, expected a directory with runnable.yml: %s", currentPath)
 }
 
-// ExecuteContext executes a runnable.
+// ExecuteContext executes a runnable on the standard streams and records it in
+// the run history. Service runnables are supervised until interrupted.
 func ExecuteContext(ctx *ExecutionContext, args []string) error {
+	return ExecuteContextObserved(ctx, args, nil)
+}
+
+// ExecuteContextObserved is ExecuteContext reporting the progress of the run
+// to observer, which may be nil.
+func ExecuteContextObserved(ctx *ExecutionContext, args []string, observer Observer) error {
+	e := &Executor{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr, Observer: observer, detachedID: takeDetachedID()}
+	c := context.Background()
+	if isService(ctx.Config) {
+		var stop context.CancelFunc
+		c, stop = signal.NotifyContext(c, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
+	_, err := e.Run(c, ctx, args)
+	return err
+}
+
+// executeRecorded runs a runnable and records it in the run history.
+func executeRecorded(c context.Context, ctx *ExecutionContext, args []string, opts runOptions) error {
+	start := time.Now()
+	err := execute(c, ctx, args, opts)
+	recordHistory(ctx, args, start, err, opts.stdout)
+	return err
+}
+
+// execute runs the hooks and the command of a runnable.
+// Cancelling c terminates the command currently running.
+func execute(c context.Context, ctx *ExecutionContext, args []string, opts runOptions) error {
 	cfg := ctx.Config
 
+	// the sources are fingerprinted as the run starts, and the fingerprint
+	// saved once it succeeds
+	var fingerprint string
+	if len(cfg.Sources) > 0 {
+		var err error
+		if fingerprint, err = computeFingerprint(ctx, args); err != nil {
+			return err
+		}
+	}
+	if fingerprint != "" && !ctx.Force {
+		status, err := checkFingerprint(ctx, fingerprint)
+		if err != nil {
+			return err
+		}
+		if status.Status == StatusUpToDate {
+			_, _ = fmt.Fprintf(opts.stdout, "Runnable '%s' is up to date, skipping (use --force to run anyway).\n", runnableName(ctx))
+			if opts.result != nil {
+				opts.result.UpToDate = true
+			}
+			return nil
+		}
+	}
+
+	limits, err := resolveLimits(cfg.Limits)
+	if err != nil {
+		return fmt.Errorf("invalid limits: %w", err)
+	}
+	opts.limits = limits
+
+	sandbox, err := resolveSandbox(ctx)
+	if err != nil {
+		return err
+	}
+	opts.sandbox = sandbox
+
+	if err := confirmRun(ctx, opts); err != nil {
+		return err
+	}
+
+	ctx, err = promptMissingEnv(ctx, opts)
+	if err != nil {
+		return err
+	}
+	ctx, err = renderTemplates(ctx, args)
+	if err != nil {
+		return err
+	}
+	cfg = ctx.Config
+
+	if cfg.Log.Enabled {
+		logFile, err := openRunLog(ctx, opts.stdout)
+		if err != nil {
+			_, _ = fmt.Fprintf(opts.stdout, "Warning: failed to open run log: %v\n", err)
+		} else {
+			defer func() { _ = logFile.Close() }()
+			opts.stdout = io.MultiWriter(opts.stdout, logFile)
+			opts.stderr = io.MultiWriter(opts.stderr, logFile)
+			if opts.output != nil {
+				opts.output = io.MultiWriter(opts.output, logFile)
+			}
+		}
+	}
+
+	env := buildEnv(ctx)
+
+	if ctx.BeforeEach != "" {
+		start := opts.startPhase(PhaseBeforeEach, ctx.BeforeEach)
+		err := executeOrShell(c, ctx.BeforeEach, args, env, ctx.CollectionPath, opts)
+		opts.finishPhase(PhaseBeforeEach, ctx.BeforeEach, start, err)
+		if err != nil {
+			return fmt.Errorf("collection pre-hook failed: %s: %w", ctx.BeforeEach, err)
+		}
+	}
+
 	if cfg.Before != "" {
-		if err := executeOrShell(cfg.Before, args, ctx.Environments, ctx.RunnablePath); err != nil {
+		start := opts.startPhase(PhaseBefore, cfg.Before)
+		err := executeOrShell(c, cfg.Before, args, env, ctx.RunnablePath, opts)
+		opts.finishPhase(PhaseBefore, cfg.Before, start, err)
+		if err != nil {
 			return fmt.Errorf("pre-hook failed: %s: %w", cfg.Before, err)
 		}
 	}
 
-	if cfg.Run == "" {
-		return fmt.Errorf("no 'run' command specified in runnable.yml")
+	if err := checkRunCommand(cfg); err != nil {
+		return err
+	}
+
+	if len(cfg.WaitFor) > 0 {
+		start := time.Now()
+		err := waitConditions(c, ctx, env, opts)
+		opts.result.record(PhaseWait, "", start, err)
+		if err != nil {
+			return err
+		}
 	}
 
-	if err := executeOrShell(cfg.Run, args, ctx.Environments, ctx.RunnablePath); err != nil {
+	afterCtx := c
+	command := runLabel(cfg)
+	start := opts.startPhase(PhaseRun, command)
+	switch {
+	case len(cfg.Pipeline) > 0:
+		var stages []*ExecutionContext
+		if stages, err = ResolvePipeline(ctx.Collection, cfg.Pipeline); err == nil {
+			for i, stage := range stages {
+				stages[i] = inheritRunSettings(stage, ctx)
+			}
+			_, err = runPipeline(c, stages, args, opts.forRun())
+		}
+	case isService(cfg):
+		err = superviseRun(c, ctx, args, env, opts)
+		// a stopped service still runs its after hook
+		afterCtx = context.WithoutCancel(c)
+	default:
+		err = executeOrShell(c, cfg.Run, args, env, ctx.RunnablePath, opts.forRun())
+	}
+	opts.finishPhase(PhaseRun, command, start, err)
+	if err != nil {
 		return fmt.Errorf("execution failed: %w", err)
 	}
 
 	if cfg.After != "" {
-		if err := executeOrShell(cfg.After, args, ctx.Environments, ctx.RunnablePath); err != nil {
-			fmt.Printf("Warning: post-hook failed: %s: %v\n", cfg.After, err)
+		start := opts.startPhase(PhaseAfter, cfg.After)
+		err := executeOrShell(afterCtx, cfg.After, args, env, ctx.RunnablePath, opts)
+		opts.finishPhase(PhaseAfter, cfg.After, start, err)
+		if err != nil {
+			_, _ = fmt.Fprintf(opts.stdout, "Warning: post-hook failed: %s: %v\n", cfg.After, err)
+		}
+	}
+
+	if ctx.AfterEach != "" {
+		start := opts.startPhase(PhaseAfterEach, ctx.AfterEach)
+		err := executeOrShell(afterCtx, ctx.AfterEach, args, env, ctx.CollectionPath, opts)
+		opts.finishPhase(PhaseAfterEach, ctx.AfterEach, start, err)
+		if err != nil {
+			_, _ = fmt.Fprintf(opts.stdout, "Warning: collection post-hook failed: %s: %v\n", ctx.AfterEach, err)
+		}
+	}
+
+	if fingerprint != "" {
+		if err := saveFingerprint(ctx, fingerprint); err != nil {
+			_, _ = fmt.Fprintf(opts.stdout, "Warning: failed to save fingerprint: %v\n", err)
 		}
 	}
 
 	return nil
 }
 
-func runScript(path string, args []string, envs map[string]string, dir string) error {
-	cmd := exec.Command(path, args...)
-	cmd.Dir = dir
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// checkRunCommand reports whether cfg declares exactly one of run and pipeline.
+func checkRunCommand(cfg *config.RunnableConfig) error {
+	if len(cfg.Pipeline) > 0 {
+		if cfg.Run != "" {
+			return fmt.Errorf("'run' and 'pipeline' cannot both be specified in runnable.yml")
+		}
+		return nil
+	}
+	if cfg.Run == "" {
+		return fmt.Errorf("no 'run' command specified in runnable.yml")
+	}
+	return nil
+}
 
-	cmd.Env = os.Environ()
-	for k, v := range envs {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+// runLabel describes the run phase of cfg.
+func runLabel(cfg *config.RunnableConfig) string {
+	if len(cfg.Pipeline) > 0 {
+		return strings.Join(cfg.Pipeline, " | ")
 	}
+	return cfg.Run
+}
 
-	return cmd.Run()
+// runOptions holds how the commands of a run are started.
+type runOptions struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// input and output, when set, are the stdin and stdout of the run command
+	// alone, like the pipes of a pipeline stage: hooks and messages use stdin
+	// and stdout.
+	input  io.Reader
+	output io.Writer
+	// processGroup starts each command in its own process group, so that
+	// cancellation terminates every process it spawned.
+	processGroup bool
+	// limits are applied to every command started, nil for none.
+	limits *resourceLimits
+	// sandbox restricts every command started, nil for none.
+	sandbox *sandboxPolicy
+	// detachedID is the id of the background run being executed, 0 in the foreground.
+	detachedID int
+	// workDir overrides the working directory of the commands.
+	workDir string
+	// result collects the outcome of each phase, nil when not needed.
+	result *Result
+	// events reports the progress of the run, nil when nobody observes it.
+	events *eventEmitter
 }
 
-func runShell(command string, args []string, envs map[string]string, dir string) error {
+func defaultRunOptions() runOptions {
+	return runOptions{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+}
 
-	shellCmd := exec.Command("/bin/sh", "-c", command, "inline-script")
-	shellCmd.Args = append(shellCmd.Args, args...)
-	shellCmd.Dir = dir
-	shellCmd.Stdin = os.Stdin
-	shellCmd.Stdout = os.Stdout
-	shellCmd.Stderr = os.Stderr
+// quiet returns the options of a check whose exit code is all that matters:
+// no output, but the sandbox and limits of the run.
+func (o runOptions) quiet() runOptions {
+	return runOptions{stdout: io.Discard, stderr: io.Discard, processGroup: true, limits: o.limits, sandbox: o.sandbox}
+}
 
-	shellCmd.Env = os.Environ()
-	for k, v := range envs {
-		shellCmd.Env = append(shellCmd.Env, fmt.Sprintf("%s=%s", k, v))
+// forRun returns the options the run command starts with.
+func (o runOptions) forRun() runOptions {
+	if o.input != nil {
+		o.stdin = o.input
+	}
+	if o.output != nil {
+		o.stdout = o.output
+	}
+	return o
+}
+
+// killGracePeriod is how long a cancelled process group gets between SIGTERM and SIGKILL.
+const killGracePeriod = 5 * time.Second
+
+func runScript(c context.Context, path string, args []string, env []string, dir string, opts runOptions) error {
+	argv, err := scriptArgv(path, args)
+	if err != nil {
+		return err
 	}
+	cmd := exec.CommandContext(c, argv[0], argv[1:]...)
+	prepareCommand(cmd, env, dir, opts)
 
-	return shellCmd.Run()
+	return runCommand(cmd, opts)
 }
 
-func executeOrShell(command string, args []string, envs map[string]string, dir string) error {
+func runShell(c context.Context, command string, args []string, env []string, dir string, opts runOptions) error {
+	shellCmd := exec.CommandContext(c, shellPath, shellArgs(command, args)...)
+	prepareCommand(shellCmd, env, dir, opts)
+
+	return runCommand(shellCmd, opts)
+}
+
+// prepareCommand applies the working dir, environment and run options to cmd.
+func prepareCommand(cmd *exec.Cmd, env []string, dir string, opts runOptions) {
+	cmd.Dir = dir
+	if opts.workDir != "" {
+		cmd.Dir = opts.workDir
+	}
+	cmd.Stdin = opts.stdin
+	cmd.Stdout = opts.stdout
+	cmd.Stderr = opts.stderr
+	cmd.Env = env
+
+	if opts.processGroup {
+		setProcessGroup(cmd)
+	}
+}
+
+// limitsStub holds a command back until its resource limits are applied: it
+// waits for the end of its input on the given descriptor, then replaces itself
+// with the command, which keeps the limits.
+const limitsStub = `read _ <&%d; exec "$@" %d<&-`
+
+// runCommand runs cmd with the sandbox and resource limits of opts. Limits are
+// applied before the command executes, so they bind it and everything it spawns.
+func runCommand(cmd *exec.Cmd, opts runOptions) error {
+	var release *os.File
+	if opts.limits != nil && cmd.Err == nil {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return fmt.Errorf("failed to apply resource limits: %w", err)
+		}
+		defer func() { _ = r.Close() }()
+		defer func() { _ = w.Close() }()
+		fd := 3 + len(cmd.ExtraFiles)
+		cmd.ExtraFiles = append(cmd.ExtraFiles, r)
+		cmd.Args = append([]string{shellPath, "-c", fmt.Sprintf(limitsStub, fd, fd), "sh", cmd.Path}, cmd.Args[1:]...)
+		cmd.Path = shellPath
+		release = w
+	}
+
+	if opts.sandbox != nil {
+		if err := startSandboxed(cmd, opts.sandbox); err != nil {
+			return err
+		}
+	} else if err := cmd.Start(); err != nil {
+		return err
+	}
+	if release == nil {
+		return cmd.Wait()
+	}
+	if err := applyLimits(cmd.Process.Pid, opts.limits); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("failed to apply resource limits: %w", err)
+	}
+	_ = release.Close()
+	return cmd.Wait()
+}
+
+func executeOrShell(c context.Context, command string, args []string, env []string, dir string, opts runOptions) error {
+	if cmdPath, ok := scriptPath(command, dir); ok {
+		return runScript(c, cmdPath, args, env, dir, opts)
+	}
+	return runShell(c, command, args, env, dir, opts)
+}
+
+// shellPath is the interpreter used for inline commands.
+const shellPath = "/bin/sh"
+
+// scriptPath reports whether command refers to an existing file inside dir.
+func scriptPath(command, dir string) (string, bool) {
 	cmdPath := filepath.Join(dir, command)
 	info, err := os.Stat(cmdPath)
-	isScript := err == nil && !info.IsDir()
-
-	if isScript {
-		return runScript(cmdPath, args, envs, dir)
+	if err != nil || info.IsDir() {
+		return "", false
 	}
-	return runShell(command, args, envs, dir)
+	return cmdPath, true
+}
+
+// shellArgs builds the arguments passed to shellPath for an inline command.
+func shellArgs(command string, args []string) []string {
+	return append([]string{"-c", command, "inline-script"}, args...)
 }
@@ -1,8 +1,11 @@
 package core
 
 import (
+	"bytes"
+	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/brsyuksel/shellican/pkg/config"
@@ -146,3 +149,64 @@ echo "$@" > args.out
 		t.Errorf("Expected args '%s', got '%s'", expected, outStr)
 	}
 }
+
+func TestExecute_CollectionHooks(t *testing.T) {
+	colDir := writeTestRunnables(t, map[string]string{
+		"wrapped": "before: echo before\nrun: echo run\nafter: echo after\n",
+		"optout":  "run: echo run\nskip_collection_hooks: true\n",
+	})
+	colCfg, err := config.LoadCollectionConfig(colDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	colCfg.BeforeEach = `echo "before_each $(basename "$(pwd)") $1"`
+	colCfg.AfterEach = "echo after_each; exit 1"
+	if err := config.SaveCollectionConfig(colDir, colCfg); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := ResolveCommand("col", []string{"wrapped"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	var stdout bytes.Buffer
+	result, err := (&Executor{Stdout: &stdout, SkipHistory: true}).Run(context.Background(), ctx, []string{"arg"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := "before_each col arg\nbefore\nrun\nafter\nafter_each\nWarning: collection post-hook failed"
+	if !strings.HasPrefix(stdout.String(), want) {
+		t.Errorf("expected output to start with %q, got %q", want, stdout.String())
+	}
+	if phase := result.Phase(PhaseAfterEach); phase == nil || phase.ExitCode != 1 {
+		t.Errorf("expected failed after_each phase, got %+v", result.Phases)
+	}
+
+	colCfg.BeforeEach = "exit 2"
+	if err := config.SaveCollectionConfig(colDir, colCfg); err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = ResolveCommand("col", []string{"wrapped"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	stdout.Reset()
+	if _, err := (&Executor{Stdout: &stdout, SkipHistory: true}).Run(context.Background(), ctx, nil); err == nil || !strings.Contains(err.Error(), "collection pre-hook failed") {
+		t.Errorf("expected failed before_each to abort, got %v", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected nothing to run after a failed before_each, got %q", stdout.String())
+	}
+
+	ctx, err = ResolveCommand("col", []string{"optout"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	stdout.Reset()
+	if _, err := (&Executor{Stdout: &stdout, SkipHistory: true}).Run(context.Background(), ctx, nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if stdout.String() != "run\n" {
+		t.Errorf("expected collection hooks to be skipped, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,273 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// Step modes reported by ExplainContext.
+const (
+	ModeScript   = "script"
+	ModeShell    = "shell"
+	ModePipeline = "pipeline"
+)
+
+// PlanStep describes a single command ExecuteContext would start.
+type PlanStep struct {
+	Phase     string   `json:"phase"`
+	Command   string   `json:"command"`
+	Mode      string   `json:"mode"`
+	Argv      []string `json:"argv"`
+	Dir       string   `json:"dir"`
+	OnFailure string   `json:"on_failure"`
+}
+
+// PlanVariable is an environment variable along with where it came from.
+type PlanVariable struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// PlanSandbox describes the sandbox the commands of a run are started in.
+type PlanSandbox struct {
+	Writable []string `json:"writable"`
+	Network  bool     `json:"network"`
+}
+
+// ExecutionPlan describes what ExecuteContext would do without running anything.
+type ExecutionPlan struct {
+	Collection  string         `json:"collection,omitempty"`
+	Runnable    string         `json:"runnable,omitempty"`
+	Steps       []PlanStep     `json:"steps"`
+	Environment []PlanVariable `json:"environment"`
+	// MissingRequirements are the requirements that would stop the run.
+	MissingRequirements []string `json:"missing_requirements,omitempty"`
+	// VersionChecks are the version commands run before the run starts.
+	VersionChecks []string `json:"version_checks,omitempty"`
+	// WaitFor describes the conditions waited for before the run command.
+	WaitFor []string `json:"wait_for,omitempty"`
+	// Sandbox is nil when the run is not sandboxed.
+	Sandbox *PlanSandbox `json:"sandbox,omitempty"`
+	// Limits are the resource limits of every command, as name=value.
+	Limits []string `json:"limits,omitempty"`
+	// Matrix lists the combinations the steps run once for, if any.
+	Matrix []map[string]string `json:"matrix,omitempty"`
+}
+
+// ExplainContext builds the execution plan of a runnable.
+func ExplainContext(ctx *ExecutionContext, args []string) (*ExecutionPlan, error) {
+	return ExplainContextWithMatrix(ctx, args, nil)
+}
+
+// ExplainContextWithMatrix builds the execution plan of a runnable run with
+// the given matrix overrides, as RunMatrix would run it.
+func ExplainContextWithMatrix(ctx *ExecutionContext, args []string, overrides map[string][]string) (*ExecutionPlan, error) {
+	if err := checkRunCommand(ctx.Config); err != nil {
+		return nil, err
+	}
+	limits, err := resolveLimits(ctx.Config.Limits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid limits: %w", err)
+	}
+	sandbox, err := resolveSandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, cond := range ctx.Config.WaitFor {
+		if _, _, err := waitCheck(cond, ctx.RunnablePath, nil, runOptions{}); err != nil {
+			return nil, err
+		}
+	}
+	var matrix map[string][]string
+	if HasMatrix(ctx, overrides) {
+		if matrix, err = mergeMatrix(ctx, overrides); err != nil {
+			return nil, err
+		}
+	}
+	ctx, err = renderTemplates(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	cfg := ctx.Config
+
+	plan := &ExecutionPlan{
+		Collection: ctx.Collection,
+		Runnable:   ctx.Runnable,
+	}
+
+	if ctx.BeforeEach != "" {
+		plan.Steps = append(plan.Steps, planStep("before_each", ctx.BeforeEach, args, ctx.CollectionPath, "abort"))
+	}
+	if cfg.Before != "" {
+		plan.Steps = append(plan.Steps, planStep("before", cfg.Before, args, ctx.RunnablePath, "abort"))
+	}
+	if len(cfg.Pipeline) > 0 {
+		plan.Steps = append(plan.Steps, PlanStep{
+			Phase:     "run",
+			Command:   runLabel(cfg),
+			Mode:      ModePipeline,
+			Argv:      cfg.Pipeline,
+			Dir:       ctx.RunnablePath,
+			OnFailure: "abort",
+		})
+	} else {
+		plan.Steps = append(plan.Steps, planStep("run", cfg.Run, args, ctx.RunnablePath, "abort"))
+	}
+	if cfg.After != "" {
+		plan.Steps = append(plan.Steps, planStep("after", cfg.After, args, ctx.RunnablePath, "warn"))
+	}
+	if ctx.AfterEach != "" {
+		plan.Steps = append(plan.Steps, planStep("after_each", ctx.AfterEach, args, ctx.CollectionPath, "warn"))
+	}
+
+	plan.Environment = resolveEnvironment(ctx)
+
+	envs := requirementEnv(ctx, overrides)
+	plan.MissingRequirements = append(missingRequirements(ctx.CollectionRequires, ctx.CollectionPath, envs),
+		missingRequirements(cfg.Requires, ctx.RunnablePath, envs)...)
+	for _, bin := range versionRequirements(ctx) {
+		plan.VersionChecks = append(plan.VersionChecks, fmt.Sprintf("%s at least %s: %s", bin.Name, bin.MinVersion, versionCommand(bin)))
+	}
+
+	for _, cond := range cfg.WaitFor {
+		_, desc, _ := waitCheck(cond, ctx.RunnablePath, nil, runOptions{})
+		timeout, interval := cond.Timeout, cond.Interval
+		if timeout <= 0 {
+			timeout = defaultWaitTimeout
+		}
+		if interval <= 0 {
+			interval = defaultWaitInterval
+		}
+		plan.WaitFor = append(plan.WaitFor, fmt.Sprintf("%s (every %s, up to %s)", desc, interval, timeout))
+	}
+	if sandbox != nil {
+		plan.Sandbox = &PlanSandbox{Writable: sandbox.writable, Network: sandbox.network}
+	}
+	if limits != nil {
+		plan.Limits = planLimits(cfg.Limits)
+	}
+	if matrix != nil {
+		plan.Matrix = expandMatrix(matrix)
+	}
+	return plan, nil
+}
+
+// planLimits lists the limits set in cfg as name=value, named as in runnable.yml.
+func planLimits(cfg config.LimitsConfig) []string {
+	var limits []string
+	add := func(name string, set bool, value any) {
+		if set {
+			limits = append(limits, fmt.Sprintf("%s=%v", name, value))
+		}
+	}
+	add("cpu_seconds", cfg.CPUSeconds != 0, cfg.CPUSeconds)
+	add("memory", cfg.Memory != "", cfg.Memory)
+	add("open_files", cfg.OpenFiles != 0, cfg.OpenFiles)
+	add("max_processes", cfg.MaxProcesses != 0, cfg.MaxProcesses)
+	add("nice", cfg.Nice != 0, cfg.Nice)
+	add("ionice", cfg.IONice != "", cfg.IONice)
+	return limits
+}
+
+// planStep mirrors the decision executeOrShell makes for a command.
+func planStep(phase, command string, args []string, dir, onFailure string) PlanStep {
+	step := PlanStep{
+		Phase:     phase,
+		Command:   command,
+		Dir:       dir,
+		OnFailure: onFailure,
+	}
+	if cmdPath, ok := scriptPath(command, dir); ok {
+		step.Mode = ModeScript
+		argv, err := scriptArgv(cmdPath, args)
+		if err != nil {
+			argv = append([]string{cmdPath}, args...)
+		}
+		step.Argv = argv
+	} else {
+		step.Mode = ModeShell
+		step.Argv = append([]string{shellPath}, shellArgs(command, args)...)
+	}
+	return step
+}
+
+// PrintPlan writes a human readable execution plan.
+func PrintPlan(w io.Writer, plan *ExecutionPlan) error {
+	if plan.Collection != "" {
+		_, _ = fmt.Fprintf(w, "Runnable:   %s (Collection: %s)\n", plan.Runnable, plan.Collection)
+	}
+
+	for _, step := range plan.Steps {
+		_, _ = fmt.Fprintf(w, "\n[%s] %s\n", step.Phase, step.Command)
+		_, _ = fmt.Fprintf(w, "  Mode:       %s\n", step.Mode)
+		_, _ = fmt.Fprintf(w, "  Argv:       %q\n", step.Argv)
+		_, _ = fmt.Fprintf(w, "  Dir:        %s\n", step.Dir)
+		_, _ = fmt.Fprintf(w, "  On failure: %s\n", step.OnFailure)
+	}
+
+	_, _ = fmt.Fprintln(w, "\nEnvironment:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SOURCE\tNAME\tVALUE")
+	for _, v := range plan.Environment {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Source, v.Name, v.Value)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+
+	if len(plan.WaitFor) > 0 {
+		_, _ = fmt.Fprintln(w, "\nWaits for (before run):")
+		for _, cond := range plan.WaitFor {
+			_, _ = fmt.Fprintf(w, "  - %s\n", cond)
+		}
+	}
+	if plan.Sandbox != nil || len(plan.Limits) > 0 {
+		_, _ = fmt.Fprintln(w)
+	}
+	if plan.Sandbox != nil {
+		network := "allowed"
+		if !plan.Sandbox.Network {
+			network = "TCP denied"
+		}
+		_, _ = fmt.Fprintf(w, "Sandbox:    writable %q, network %s\n", plan.Sandbox.Writable, network)
+	}
+	if len(plan.Limits) > 0 {
+		_, _ = fmt.Fprintf(w, "Limits:     %s\n", strings.Join(plan.Limits, " "))
+	}
+	if len(plan.Matrix) > 0 {
+		_, _ = fmt.Fprintf(w, "\nMatrix (%d runs):\n", len(plan.Matrix))
+		for _, values := range plan.Matrix {
+			_, _ = fmt.Fprintf(w, "  - %s\n", matrixLabel(values))
+		}
+	}
+
+	if len(plan.VersionChecks) > 0 {
+		_, _ = fmt.Fprintln(w, "\nVersion checks (not run):")
+		for _, check := range plan.VersionChecks {
+			_, _ = fmt.Fprintf(w, "  - %s\n", check)
+		}
+	}
+	if len(plan.MissingRequirements) > 0 {
+		_, _ = fmt.Fprintln(w, "\nMissing requirements:")
+		for _, missing := range plan.MissingRequirements {
+			_, _ = fmt.Fprintf(w, "  - %s\n", missing)
+		}
+	}
+	return nil
+}
+
+// PrintPlanJSON writes the execution plan as indented JSON.
+func PrintPlanJSON(w io.Writer, plan *ExecutionPlan) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(plan); err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestExplainContext(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_EXPLAIN_OS", "os-value")
+
+	if err := os.WriteFile(filepath.Join(tempDir, "main.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	ctx := &ExecutionContext{
+		Collection:   "col",
+		Runnable:     "run",
+		RunnablePath: tempDir,
+		Config: &config.RunnableConfig{
+			Before: "touch before.out",
+			Run:    "main.sh",
+			After:  "echo after",
+		},
+		Environments: map[string]string{
+			"COL_ENV": "col",
+			"RUN_ENV": "run",
+		},
+		EnvironmentSources: map[string]string{
+			"COL_ENV": EnvSourceCollection,
+			"RUN_ENV": EnvSourceRunnable,
+		},
+	}
+
+	plan, err := ExplainContext(ctx, []string{"a1"})
+	if err != nil {
+		t.Fatalf("ExplainContext failed: %v", err)
+	}
+
+	if len(plan.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d", len(plan.Steps))
+	}
+	if plan.Steps[0].Mode != ModeShell || plan.Steps[0].Argv[0] != "/bin/sh" {
+		t.Errorf("Expected before hook to run via shell, got %+v", plan.Steps[0])
+	}
+	run := plan.Steps[1]
+	if run.Mode != ModeScript || run.Argv[0] != filepath.Join(tempDir, "main.sh") || run.Argv[1] != "a1" {
+		t.Errorf("Unexpected run step: %+v", run)
+	}
+	if plan.Steps[2].OnFailure != "warn" {
+		t.Errorf("Expected after hook failures to warn, got %s", plan.Steps[2].OnFailure)
+	}
+
+	sources := make(map[string]string)
+	for _, v := range plan.Environment {
+		sources[v.Name] = v.Source
+	}
+	if sources["COL_ENV"] != EnvSourceCollection || sources["RUN_ENV"] != EnvSourceRunnable || sources["SHELLICAN_EXPLAIN_OS"] != EnvSourceOS {
+		t.Errorf("Unexpected environment sources: %v", sources)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintPlanJSON(&buf, plan); err != nil {
+		t.Fatalf("PrintPlanJSON failed: %v", err)
+	}
+	var decoded ExecutionPlan
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON plan: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "before.out")); !os.IsNotExist(err) {
+		t.Error("Explain must not execute anything")
+	}
+}
+
+func TestExplainContext_NoRun(t *testing.T) {
+	ctx := &ExecutionContext{
+		RunnablePath: "/tmp",
+		Config:       &config.RunnableConfig{},
+	}
+	if _, err := ExplainContext(ctx, nil); err == nil {
+		t.Error("Expected error for missing run command")
+	}
+}
+
+func TestExplainContextWithMatrix_RunSettings(t *testing.T) {
+	if err := checkSandboxSupported(); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+
+	network := false
+	dir := t.TempDir()
+	ctx := &ExecutionContext{
+		RunnablePath: dir,
+		Config: &config.RunnableConfig{
+			Run:     "echo \"$OS/$ARCH\"",
+			WaitFor: []config.WaitCondition{{TCP: "localhost:5432", Timeout: 5 * time.Second}},
+			Sandbox: config.SandboxConfig{Enabled: true, Writable: []string{"out"}, Network: &network},
+			Limits:  config.LimitsConfig{Memory: "512M", Nice: 5},
+			Matrix:  map[string][]string{"OS": {"linux", "darwin"}, "ARCH": {"amd64"}},
+		},
+	}
+
+	plan, err := ExplainContextWithMatrix(ctx, nil, map[string][]string{"ARCH": {"amd64", "arm64"}})
+	if err != nil {
+		t.Fatalf("ExplainContextWithMatrix failed: %v", err)
+	}
+	if len(plan.WaitFor) != 1 || plan.WaitFor[0] != "tcp localhost:5432 (every 1s, up to 5s)" {
+		t.Errorf("Unexpected wait conditions: %q", plan.WaitFor)
+	}
+	if plan.Sandbox == nil || plan.Sandbox.Network || len(plan.Sandbox.Writable) != 1 || plan.Sandbox.Writable[0] != filepath.Join(dir, "out") {
+		t.Errorf("Unexpected sandbox: %+v", plan.Sandbox)
+	}
+	if strings.Join(plan.Limits, " ") != "memory=512M nice=5" {
+		t.Errorf("Unexpected limits: %q", plan.Limits)
+	}
+	var combinations []string
+	for _, values := range plan.Matrix {
+		combinations = append(combinations, matrixLabel(values))
+	}
+	if strings.Join(combinations, ", ") != "ARCH=amd64 OS=linux, ARCH=amd64 OS=darwin, ARCH=arm64 OS=linux, ARCH=arm64 OS=darwin" {
+		t.Errorf("Expected the overrides to be expanded, got %q", combinations)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintPlan(&buf, plan); err != nil {
+		t.Fatalf("PrintPlan failed: %v", err)
+	}
+	for _, want := range []string{"tcp localhost:5432", "network TCP denied", "memory=512M", "Matrix (4 runs)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected the plan to mention %q, got %s", want, buf.String())
+		}
+	}
+
+	ctx.Config.Limits.Nice = 40
+	if _, err := ExplainContext(ctx, nil); err == nil {
+		t.Error("Expected error for invalid limits")
+	}
+}
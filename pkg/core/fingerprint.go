@@ -0,0 +1,228 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// Staleness states reported by CheckUpToDate.
+const (
+	StatusUpToDate  = "up-to-date"
+	StatusStale     = "stale"
+	StatusUntracked = "untracked"
+)
+
+// fingerprintState is what gets stored after a successful run.
+type fingerprintState struct {
+	Fingerprint string    `json:"fingerprint"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UpToDateStatus describes whether a runnable needs to run again.
+type UpToDateStatus struct {
+	Status string
+	Reason string
+}
+
+// fingerprintPath resolves where the fingerprint of a runnable is stored.
+func fingerprintPath(ctx *ExecutionContext) (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	// each combination of a matrix run has a fingerprint of its own
+	suffix := ""
+	if values := matrixValues(ctx); len(values) > 0 {
+		sum := sha256.Sum256([]byte(matrixLabel(values)))
+		suffix = "@" + hex.EncodeToString(sum[:8])
+	}
+	if ctx.Collection != "" {
+		return filepath.Join(stateDir, "fingerprints", ctx.Collection, ctx.Runnable+suffix+".json"), nil
+	}
+	// runnables not resolved from a collection are keyed by their path
+	sum := sha256.Sum256([]byte(ctx.RunnablePath))
+	return filepath.Join(stateDir, "fingerprints", "_", hex.EncodeToString(sum[:8])+suffix+".json"), nil
+}
+
+// computeFingerprint hashes the sources of a runnable and, when configured, its environment and args.
+func computeFingerprint(ctx *ExecutionContext, args []string) (string, error) {
+	cfg := ctx.Config
+	files, err := expandGlobs(ctx.RunnablePath, cfg.Sources)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand sources: %w", err)
+	}
+
+	h := sha256.New()
+	for _, file := range files {
+		rel, _ := filepath.Rel(ctx.RunnablePath, file)
+		_, _ = fmt.Fprintf(h, "file\x00%s\x00", rel)
+		if err := hashFile(h, file); err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.Fingerprint.Env {
+		keys := make([]string, 0, len(ctx.Environments))
+		for k := range ctx.Environments {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			_, _ = fmt.Fprintf(h, "env\x00%s=%s\x00", k, ctx.Environments[k])
+		}
+	}
+
+	if cfg.Fingerprint.Args {
+		_, _ = fmt.Fprintf(h, "args\x00%s\x00", strings.Join(args, "\x00"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read source: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("failed to read source: %w", err)
+	}
+	return nil
+}
+
+// CheckUpToDate reports whether the sources of a runnable changed since its last
+// successful run and whether its outputs still exist.
+func CheckUpToDate(ctx *ExecutionContext, args []string) (*UpToDateStatus, error) {
+	if len(ctx.Config.Sources) == 0 {
+		return &UpToDateStatus{Status: StatusUntracked, Reason: "no sources declared"}, nil
+	}
+	current, err := computeFingerprint(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return checkFingerprint(ctx, current)
+}
+
+// checkFingerprint is CheckUpToDate with the current fingerprint of the sources.
+func checkFingerprint(ctx *ExecutionContext, current string) (*UpToDateStatus, error) {
+	path, err := fingerprintPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := loadFingerprint(path)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return &UpToDateStatus{Status: StatusStale, Reason: "never run"}, nil
+	}
+	if current != stored.Fingerprint {
+		return &UpToDateStatus{Status: StatusStale, Reason: "sources changed"}, nil
+	}
+
+	for _, pattern := range ctx.Config.Outputs {
+		matches, err := expandGlobs(ctx.RunnablePath, []string{pattern})
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand outputs: %w", err)
+		}
+		if len(matches) == 0 {
+			return &UpToDateStatus{Status: StatusStale, Reason: fmt.Sprintf("output '%s' missing", pattern)}, nil
+		}
+	}
+
+	return &UpToDateStatus{Status: StatusUpToDate, Reason: "last run " + stored.UpdatedAt.Format(time.DateTime)}, nil
+}
+
+func loadFingerprint(path string) (*fingerprintState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read fingerprint: %w", err)
+	}
+	var state fingerprintState
+	if err := json.Unmarshal(data, &state); err != nil {
+		// a corrupt fingerprint only means the runnable runs again
+		return nil, nil
+	}
+	return &state, nil
+}
+
+// saveFingerprint stores the fingerprint of a runnable after a successful run.
+// It is the fingerprint of the sources as the run started, so that sources
+// edited during the run make the next run stale.
+func saveFingerprint(ctx *ExecutionContext, fingerprint string) error {
+	path, err := fingerprintPath(ctx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create fingerprint dir: %w", err)
+	}
+
+	data, err := json.Marshal(fingerprintState{Fingerprint: fingerprint, UpdatedAt: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal fingerprint: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write fingerprint: %w", err)
+	}
+	return nil
+}
+
+// ShowStatus prints whether the runnables of a collection are up to date.
+// If runnableName is empty, all runnables of the collection are shown.
+func ShowStatus(collectionName, runnableName string) error {
+	rootDir, err := getRoot()
+	if err != nil {
+		return err
+	}
+	colCfg, err := config.LoadCollectionConfig(filepath.Join(rootDir, collectionName))
+	if err != nil {
+		return fmt.Errorf("failed to load collection config: %w", err)
+	}
+	if colCfg == nil {
+		return fmt.Errorf("collection '%s' not found or invalid", collectionName)
+	}
+
+	names := colCfg.Runnables
+	if runnableName != "" {
+		names = []string{runnableName}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSTATUS\tREASON")
+	for _, name := range names {
+		var status *UpToDateStatus
+		// a status does not run version commands
+		ctx, err := ResolveCommandUnchecked(collectionName, []string{name}, nil)
+		if err == nil {
+			err = checkRequirements(ctx.CollectionRequires, ctx.CollectionPath, ctx.Config.Requires, ctx.RunnablePath, requirementEnv(ctx, nil))
+		}
+		if err == nil {
+			status, err = CheckUpToDate(ctx, nil)
+		}
+		if err != nil {
+			status = &UpToDateStatus{Status: "error", Reason: strings.ReplaceAll(err.Error(), "\n", " ")}
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", name, status.Status, status.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestExecuteContext_SkipsWhenUpToDate(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_HOME", tempDir)
+	runDir := filepath.Join(tempDir, "run")
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		t.Fatalf("Failed to create runnable dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, "input.txt"), []byte("v1"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	ctx := &ExecutionContext{
+		RunnablePath: runDir,
+		Config: &config.RunnableConfig{
+			Run:     "cp input.txt output.txt && echo x >> runs.log",
+			Sources: []string{"input.txt"},
+			Outputs: []string{"output.txt"},
+		},
+		Environments: map[string]string{},
+	}
+
+	runs := func() int {
+		data, _ := os.ReadFile(filepath.Join(runDir, "runs.log"))
+		return len(data) / 2
+	}
+
+	status, err := CheckUpToDate(ctx, nil)
+	if err != nil || status.Status != StatusStale {
+		t.Fatalf("Expected stale before first run, got %+v, %v", status, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ExecuteContext(ctx, nil); err != nil {
+			t.Fatalf("ExecuteContext failed: %v", err)
+		}
+	}
+	if runs() != 1 {
+		t.Errorf("Expected second run to be skipped, ran %d times", runs())
+	}
+
+	// changed sources
+	if err := os.WriteFile(filepath.Join(runDir, "input.txt"), []byte("v2"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	if err := ExecuteContext(ctx, nil); err != nil {
+		t.Fatalf("ExecuteContext failed: %v", err)
+	}
+	if runs() != 2 {
+		t.Errorf("Expected run after source change, ran %d times", runs())
+	}
+
+	// missing outputs
+	if err := os.Remove(filepath.Join(runDir, "output.txt")); err != nil {
+		t.Fatalf("Failed to remove output: %v", err)
+	}
+	if err := ExecuteContext(ctx, nil); err != nil {
+		t.Fatalf("ExecuteContext failed: %v", err)
+	}
+	if runs() != 3 {
+		t.Errorf("Expected run after outputs were removed, ran %d times", runs())
+	}
+
+	// forced
+	ctx.Force = true
+	if err := ExecuteContext(ctx, nil); err != nil {
+		t.Fatalf("ExecuteContext failed: %v", err)
+	}
+	if runs() != 4 {
+		t.Errorf("Expected forced run, ran %d times", runs())
+	}
+}
+
+func TestComputeFingerprint_Args(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := &ExecutionContext{
+		RunnablePath: tempDir,
+		Config: &config.RunnableConfig{
+			Sources:     []string{"*"},
+			Fingerprint: config.FingerprintConfig{Args: true},
+		},
+	}
+
+	a, err := computeFingerprint(ctx, []string{"a"})
+	if err != nil {
+		t.Fatalf("computeFingerprint failed: %v", err)
+	}
+	b, _ := computeFingerprint(ctx, []string{"b"})
+	if a == b {
+		t.Error("Expected args to change the fingerprint")
+	}
+
+	ctx.Config.Fingerprint.Args = false
+	a, _ = computeFingerprint(ctx, []string{"a"})
+	b, _ = computeFingerprint(ctx, []string{"b"})
+	if a != b {
+		t.Error("Expected args to be ignored")
+	}
+}
+
+func TestExecuteContext_SourcesEditedDuringRun(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_HOME", tempDir)
+	runDir := filepath.Join(tempDir, "run")
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		t.Fatalf("Failed to create runnable dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, "input.txt"), []byte("v1"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	ctx := &ExecutionContext{
+		RunnablePath: runDir,
+		Config: &config.RunnableConfig{
+			// the source changes after the run read it
+			Run:     "echo v2 > input.txt",
+			Sources: []string{"input.txt"},
+		},
+		Environments: map[string]string{},
+	}
+	if err := ExecuteContext(ctx, nil); err != nil {
+		t.Fatalf("ExecuteContext failed: %v", err)
+	}
+
+	status, err := CheckUpToDate(ctx, nil)
+	if err != nil || status.Status != StatusStale {
+		t.Errorf("Expected sources edited during the run to be stale, got %+v, %v", status, err)
+	}
+}
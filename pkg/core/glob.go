@@ -0,0 +1,157 @@
+package core
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// expandGlobs returns the files under baseDir matching any of patterns.
+// Patterns are relative to baseDir and, unlike filepath.Glob, support `**`
+// to match any number of directories. A matched directory stands for all files in it.
+func expandGlobs(baseDir string, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+
+	for _, pattern := range patterns {
+		matches, err := expandGlob(baseDir, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func expandGlob(baseDir, pattern string) ([]string, error) {
+	pattern, err := relativePattern(baseDir, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.Contains(pattern, "**") {
+		matches, err := filepath.Glob(filepath.Join(baseDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		return filesUnder(matches), nil
+	}
+
+	root, segments := globRoot(baseDir, pattern)
+	var matches []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		if matchSegments(segments, strings.Split(filepath.ToSlash(rel), "/")) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// relativePattern cleans pattern and makes it relative to baseDir, using slashes.
+func relativePattern(baseDir, pattern string) (string, error) {
+	if filepath.IsAbs(pattern) {
+		rel, err := filepath.Rel(baseDir, pattern)
+		if err != nil {
+			return "", err
+		}
+		pattern = rel
+	}
+	return filepath.ToSlash(filepath.Clean(pattern)), nil
+}
+
+// globRoot splits a relative pattern into the longest directory without
+// wildcards and the remaining pattern segments.
+func globRoot(baseDir, pattern string) (string, []string) {
+	segments := strings.Split(pattern, "/")
+	root := baseDir
+	for len(segments) > 0 && !hasGlobMeta(segments[0]) {
+		root = filepath.Join(root, segments[0])
+		segments = segments[1:]
+	}
+	return root, segments
+}
+
+// matchGlob reports whether path, or a directory containing it, matches a relative pattern.
+func matchGlob(baseDir, pattern, path string) bool {
+	pattern, err := relativePattern(baseDir, pattern)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(baseDir, path)
+	if err != nil {
+		return false
+	}
+	segments := strings.Split(pattern, "/")
+	pathSegments := strings.Split(filepath.ToSlash(rel), "/")
+	for i := len(pathSegments); i > 0; i-- {
+		if matchSegments(segments, pathSegments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments, where `**`
+// matches zero or more segments.
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+func hasGlobMeta(segment string) bool {
+	return strings.ContainsAny(segment, "*?[\\")
+}
+
+// filesUnder returns the given files along with every file inside the given directories.
+func filesUnder(paths []string) []string {
+	var files []string
+	for _, p := range paths {
+		_ = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	return files
+}
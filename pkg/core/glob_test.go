@@ -0,0 +1,41 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandGlobs(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, f := range []string{"main.go", "README.md", "src/a.go", "src/nested/b.go", "src/nested/c.txt"} {
+		path := filepath.Join(tempDir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(f), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		patterns []string
+		want     int
+	}{
+		{[]string{"*.go"}, 1},
+		{[]string{"**/*.go"}, 3},
+		{[]string{"src/**/*.go"}, 2},
+		{[]string{"src"}, 3},
+		{[]string{"src/**", "*.go"}, 4},
+		{[]string{"missing/**"}, 0},
+	}
+	for _, tt := range tests {
+		got, err := expandGlobs(tempDir, tt.patterns)
+		if err != nil {
+			t.Fatalf("expandGlobs(%v) failed: %v", tt.patterns, err)
+		}
+		if len(got) != tt.want {
+			t.Errorf("expandGlobs(%v) = %v, want %d files", tt.patterns, got, tt.want)
+		}
+	}
+}
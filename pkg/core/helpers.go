@@ -17,3 +17,13 @@ func getRoot() (string, error) {
 	}
 	return filepath.Join(homeDir, ".shellican"), nil
 }
+
+// getStateDir resolves the directory holding shellican's own state such as run history.
+// It lives inside the root but is hidden so it is never mistaken for a collection.
+func getStateDir() (string, error) {
+	rootDir, err := getRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(rootDir, ".state"), nil
+}
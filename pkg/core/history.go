@@ -0,0 +1,318 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// HistoryEntry is a single recorded invocation of a runnable.
+type HistoryEntry struct {
+	ID         int       `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Collection string    `json:"collection"`
+	Runnable   string    `json:"runnable"`
+	Args       []string  `json:"args"`
+	DurationMs int64     `json:"duration_ms"`
+	ExitCode   int       `json:"exit_code"`
+	Cwd        string    `json:"cwd"`
+	// Env and EnvFiles are the -e and --env-file arguments of the run. Env
+	// files are read again on rerun, so that their values stay out of history.
+	Env      []string `json:"env,omitempty"`
+	EnvFiles []string `json:"env_files,omitempty"`
+	// Sandbox, Force and Log record the --sandbox, --force and --log flags,
+	// and Matrix the combination a matrix run ran with.
+	Sandbox bool              `json:"sandbox,omitempty"`
+	Force   bool              `json:"force,omitempty"`
+	Log     bool              `json:"log,omitempty"`
+	Matrix  map[string]string `json:"matrix,omitempty"`
+}
+
+// HistoryFilter narrows down the entries returned by FilterHistory.
+type HistoryFilter struct {
+	Collection string
+	Runnable   string
+	Failed     bool
+	Since      time.Time
+	Limit      int
+}
+
+// getHistoryPath resolves the history file.
+func getHistoryPath() (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "history.jsonl"), nil
+}
+
+// recordHistory stores the outcome of ExecuteContext, reporting failures as
+// warnings to w. Runnables that were not resolved from a collection are not recorded.
+func recordHistory(ctx *ExecutionContext, args []string, start time.Time, runErr error, w io.Writer) {
+	if ctx.Collection == "" {
+		return
+	}
+
+	cwd, _ := os.Getwd()
+	entry := &HistoryEntry{
+		Timestamp:  start,
+		Collection: ctx.Collection,
+		Runnable:   ctx.Runnable,
+		Args:       args,
+		DurationMs: time.Since(start).Milliseconds(),
+		ExitCode:   exitCode(runErr),
+		Cwd:        cwd,
+		Env:        ctx.EnvAssignments,
+		Sandbox:    ctx.Sandbox,
+		Force:      ctx.Force,
+		Log:        ctx.Config.Log.Enabled,
+	}
+	if values := matrixValues(ctx); len(values) > 0 {
+		entry.Matrix = values
+	}
+	for _, file := range ctx.EnvFiles {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+		entry.EnvFiles = append(entry.EnvFiles, file)
+	}
+	if err := AppendHistory(entry); err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: failed to record history: %v\n", err)
+	}
+}
+
+// exitCode extracts the process exit code from an execution error.
+// Errors that did not come from a process exit are reported as -1.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// AppendHistory assigns the next id to entry and appends it to the history file.
+func AppendHistory(entry *HistoryEntry) error {
+	path, err := getHistoryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state dir: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	unlock, err := lockFile(f)
+	if err != nil {
+		return fmt.Errorf("failed to lock history: %w", err)
+	}
+	defer unlock()
+
+	entries, err := readHistory(f)
+	if err != nil {
+		return err
+	}
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory returns all recorded entries, oldest first.
+func LoadHistory() ([]HistoryEntry, error) {
+	path, err := getHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return readHistory(f)
+}
+
+func readHistory(f *os.File) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// skip corrupt lines instead of losing the whole history
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// FilterHistory returns the entries matching filter, oldest first.
+// When a limit is set only the most recent entries are kept.
+func FilterHistory(entries []HistoryEntry, filter HistoryFilter) []HistoryEntry {
+	var result []HistoryEntry
+	for _, e := range entries {
+		if filter.Collection != "" && e.Collection != filter.Collection {
+			continue
+		}
+		if filter.Runnable != "" && e.Runnable != filter.Runnable {
+			continue
+		}
+		if filter.Failed && e.ExitCode == 0 {
+			continue
+		}
+		if !filter.Since.IsZero() && e.Timestamp.Before(filter.Since) {
+			continue
+		}
+		result = append(result, e)
+	}
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[len(result)-filter.Limit:]
+	}
+	return result
+}
+
+// ListHistory prints the recorded runs matching filter.
+func ListHistory(filter HistoryFilter) error {
+	entries, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	entries = FilterHistory(entries, filter)
+	if len(entries) == 0 {
+		fmt.Println("No history found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tTIME\tCOLLECTION\tRUNNABLE\tARGS\tEXIT\tDURATION")
+	for _, e := range entries {
+		duration := time.Duration(e.DurationMs) * time.Millisecond
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			e.ID, e.Timestamp.Format(time.DateTime), e.Collection, e.Runnable,
+			strings.Join(e.Args, " "), e.ExitCode, duration)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	return nil
+}
+
+// FindHistoryEntry returns the entry with the given id.
+func FindHistoryEntry(id int) (*HistoryEntry, error) {
+	entries, err := LoadHistory()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("history entry %d not found", id)
+}
+
+// LastHistoryEntry returns the most recent entry.
+func LastHistoryEntry() (*HistoryEntry, error) {
+	entries, err := LoadHistory()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("history is empty")
+	}
+	return &entries[len(entries)-1], nil
+}
+
+// Rerun replays a recorded invocation from the directory it was started in,
+// with the flags and matrix combination it ran with. assumeYes skips the
+// confirmation configured on the runnable.
+func Rerun(entry *HistoryEntry, assumeYes bool) error {
+	if entry.Cwd != "" {
+		if info, err := os.Stat(entry.Cwd); err == nil && info.IsDir() {
+			if err := os.Chdir(entry.Cwd); err != nil {
+				return fmt.Errorf("failed to change directory: %w", err)
+			}
+		}
+	}
+
+	overrides, err := ParseEnvOverrides(entry.Env, entry.EnvFiles)
+	if err != nil {
+		return fmt.Errorf("failed to parse environment: %w", err)
+	}
+	ctx, err := ResolveCommandUnchecked(entry.Collection, []string{entry.Runnable}, overrides)
+	if err != nil {
+		return fmt.Errorf("failed to resolve command: %w", err)
+	}
+	ctx.AssumeYes = assumeYes
+	ctx.EnvAssignments = entry.Env
+	ctx.EnvFiles = entry.EnvFiles
+	ctx.Sandbox = entry.Sandbox
+	ctx.Force = entry.Force
+	if entry.Log {
+		ctx.Config.Log.Enabled = true
+	}
+
+	matrix := make(map[string][]string, len(entry.Matrix))
+	for name, value := range entry.Matrix {
+		if ctx.Environments == nil {
+			ctx.Environments = make(map[string]string)
+		}
+		if ctx.EnvironmentSources == nil {
+			ctx.EnvironmentSources = make(map[string]string)
+		}
+		ctx.Environments[name] = value
+		ctx.EnvironmentSources[name] = EnvSourceMatrix
+		matrix[name] = []string{value}
+	}
+	if err := CheckRequirements(ctx, matrix); err != nil {
+		return fmt.Errorf("failed to resolve command: %w", err)
+	}
+
+	label := strings.Join(entry.Args, " ")
+	if len(entry.Matrix) > 0 {
+		label = strings.TrimSpace(label + " [" + matrixLabel(entry.Matrix) + "]")
+	}
+	fmt.Printf("Rerunning #%d: %s %s %s\n", entry.ID, entry.Collection, entry.Runnable, label)
+	return ExecuteContext(ctx, entry.Args)
+}
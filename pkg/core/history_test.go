@@ -0,0 +1,175 @@
+package core
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteContext_RecordsHistory(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_HOME", tempDir)
+
+	if err := CreateCollection("col1"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	if err := CreateRunnable("col1", "run1"); err != nil {
+		t.Fatalf("setup run failed: %v", err)
+	}
+	runPath := filepath.Join(tempDir, ".shellican", "col1", "run1", "runnable.yml")
+	if err := os.WriteFile(runPath, []byte("run: exit 3"), 0644); err != nil {
+		t.Fatalf("failed to write runnable config: %v", err)
+	}
+
+	ctx, err := ResolveCommand("col1", []string{"run1"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	if err := ExecuteContext(ctx, []string{"a", "b"}); err == nil {
+		t.Fatal("Expected error for failing command")
+	}
+
+	entry, err := LastHistoryEntry()
+	if err != nil {
+		t.Fatalf("LastHistoryEntry failed: %v", err)
+	}
+	if entry.ID != 1 || entry.Collection != "col1" || entry.Runnable != "run1" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", entry.ExitCode)
+	}
+	if len(entry.Args) != 2 || entry.Args[0] != "a" {
+		t.Errorf("Unexpected args: %v", entry.Args)
+	}
+
+	if err := os.WriteFile(runPath, []byte("run: \"true\""), 0644); err != nil {
+		t.Fatalf("failed to write runnable config: %v", err)
+	}
+	if err := Rerun(entry, false); err != nil {
+		t.Fatalf("Rerun failed: %v", err)
+	}
+	second, err := FindHistoryEntry(2)
+	if err != nil {
+		t.Fatalf("FindHistoryEntry failed: %v", err)
+	}
+	if second.ExitCode != 0 {
+		t.Errorf("Expected rerun to succeed, got exit code %d", second.ExitCode)
+	}
+
+	// history directory must not show up as a collection
+	if err := ListCollections(); err != nil {
+		t.Errorf("ListCollections failed: %v", err)
+	}
+}
+
+func TestFilterHistory(t *testing.T) {
+	now := time.Now()
+	entries := []HistoryEntry{
+		{ID: 1, Collection: "a", Runnable: "x", Timestamp: now.Add(-48 * time.Hour)},
+		{ID: 2, Collection: "a", Runnable: "y", ExitCode: 1, Timestamp: now},
+		{ID: 3, Collection: "b", Runnable: "x", Timestamp: now},
+		{ID: 4, Collection: "a", Runnable: "x", Timestamp: now},
+	}
+
+	if got := FilterHistory(entries, HistoryFilter{Collection: "a"}); len(got) != 3 {
+		t.Errorf("Expected 3 entries for collection a, got %d", len(got))
+	}
+	if got := FilterHistory(entries, HistoryFilter{Collection: "a", Runnable: "x"}); len(got) != 2 {
+		t.Errorf("Expected 2 entries for a/x, got %d", len(got))
+	}
+	if got := FilterHistory(entries, HistoryFilter{Failed: true}); len(got) != 1 || got[0].ID != 2 {
+		t.Errorf("Expected only failed entry, got %v", got)
+	}
+	if got := FilterHistory(entries, HistoryFilter{Since: now.Add(-time.Hour)}); len(got) != 3 {
+		t.Errorf("Expected 3 recent entries, got %d", len(got))
+	}
+	if got := FilterHistory(entries, HistoryFilter{Limit: 2}); len(got) != 2 || got[1].ID != 4 {
+		t.Errorf("Expected the 2 most recent entries, got %v", got)
+	}
+}
+
+func TestRerun_ReplaysEnvOverrides(t *testing.T) {
+	writeTestRunnables(t, map[string]string{
+		"deploy": "run: '[ \"$TOKEN/$FROM_FILE\" = t/f ]'\nrequires:\n  env: [TOKEN, FROM_FILE]\n",
+	})
+	envFile := filepath.Join(t.TempDir(), "deploy.env")
+	if err := os.WriteFile(envFile, []byte("FROM_FILE=f\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assignments := []string{"TOKEN=t"}
+	overrides, err := ParseEnvOverrides(assignments, []string{envFile})
+	if err != nil {
+		t.Fatalf("ParseEnvOverrides failed: %v", err)
+	}
+	ctx, err := ResolveCommandWithEnv("col", []string{"deploy"}, overrides)
+	if err != nil {
+		t.Fatalf("ResolveCommandWithEnv failed: %v", err)
+	}
+	ctx.EnvAssignments = assignments
+	ctx.EnvFiles = []string{envFile}
+	if err := ExecuteContext(ctx, nil); err != nil {
+		t.Fatalf("ExecuteContext failed: %v", err)
+	}
+
+	entry, err := LastHistoryEntry()
+	if err != nil {
+		t.Fatalf("LastHistoryEntry failed: %v", err)
+	}
+	if len(entry.Env) != 1 || entry.Env[0] != "TOKEN=t" || len(entry.EnvFiles) != 1 || entry.EnvFiles[0] != envFile {
+		t.Errorf("Expected the overrides to be recorded, got %+v", entry)
+	}
+
+	if err := Rerun(entry, false); err != nil {
+		t.Fatalf("Rerun failed: %v", err)
+	}
+	if second, _ := LastHistoryEntry(); second.ID != entry.ID+1 || second.ExitCode != 0 || len(second.Env) != 1 {
+		t.Errorf("Expected the rerun to succeed with the same overrides, got %+v", second)
+	}
+}
+
+func TestRerun_ReplaysFlagsAndMatrix(t *testing.T) {
+	colDir := writeTestRunnables(t, map[string]string{
+		"build": "run: 'echo \"$OS\" >> ran'\nsources: [runnable.yml]\nmatrix:\n  OS: [linux, darwin]\n",
+	})
+	ran := filepath.Join(colDir, "build", "ran")
+
+	ctx, err := ResolveCommand("col", []string{"build"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	ctx.Force = true
+	ctx.Config.Log.Enabled = true
+	if _, err := runMatrix(context.Background(), ctx, nil, nil, 1, runOptions{stdout: io.Discard, stderr: io.Discard}); err != nil {
+		t.Fatalf("runMatrix failed: %v", err)
+	}
+
+	entry, err := LastHistoryEntry()
+	if err != nil {
+		t.Fatalf("LastHistoryEntry failed: %v", err)
+	}
+	if !entry.Force || !entry.Log || entry.Sandbox || len(entry.Matrix) != 1 || entry.Matrix["OS"] == "" {
+		t.Fatalf("Expected the flags and combination to be recorded, got %+v", entry)
+	}
+
+	// the combination is unchanged, so only --force makes it run again
+	if err := Rerun(entry, false); err != nil {
+		t.Fatalf("Rerun failed: %v", err)
+	}
+	data, err := os.ReadFile(ran)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Fields(string(data)); len(lines) != 3 || lines[2] != entry.Matrix["OS"] {
+		t.Errorf("Expected the rerun to replay the %s combination, got %q", entry.Matrix["OS"], lines)
+	}
+	second, _ := LastHistoryEntry()
+	if !second.Force || !second.Log || second.Matrix["OS"] != entry.Matrix["OS"] {
+		t.Errorf("Expected the rerun to keep the flags and combination, got %+v", second)
+	}
+}
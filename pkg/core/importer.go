@@ -31,17 +31,29 @@ func ImportCollection(source, name string) error {
 		return fmt.Errorf("collection '%s' already exists at %s", name, targetDir)
 	}
 
+	if err := importSource(source, targetDir); err != nil {
+		return err
+	}
+
+	for _, warning := range scriptWarnings(targetDir) {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	return nil
+}
+
+// importSource imports source to target according to its type.
+func importSource(source, target string) error {
 	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "git@") || strings.HasSuffix(source, ".git") {
-		return importGit(source, targetDir)
+		return importGit(source, target)
 	}
 
 	if strings.HasSuffix(source, ".tar.gz") {
-		return importTarball(source, targetDir)
+		return importTarball(source, target)
 	}
 
 	info, err := os.Stat(source)
 	if err == nil && info.IsDir() {
-		return importFolder(source, targetDir)
+		return importFolder(source, target)
 	} else if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("error checking source: %w", err)
 	}
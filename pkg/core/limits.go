@@ -0,0 +1,165 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// resourceLimits is the validated form of config.LimitsConfig.
+// Zero values leave the corresponding limit untouched.
+type resourceLimits struct {
+	cpuSeconds   uint64
+	memory       uint64
+	openFiles    uint64
+	maxProcesses uint64
+	nice         int
+	// ioPriority is encoded as in ioprio_set(2): class<<13 | level.
+	ioPriority int
+}
+
+// I/O scheduling classes of ioprio_set(2).
+const (
+	ioClassRealtime   = 1
+	ioClassBestEffort = 2
+	ioClassIdle       = 3
+	ioClassShift      = 13
+)
+
+// resolveLimits validates the limits of a runnable. It returns nil when no
+// limit is set.
+func resolveLimits(cfg config.LimitsConfig) (*resourceLimits, error) {
+	if cfg == (config.LimitsConfig{}) {
+		return nil, nil
+	}
+
+	l := &resourceLimits{
+		cpuSeconds:   cfg.CPUSeconds,
+		openFiles:    cfg.OpenFiles,
+		maxProcesses: cfg.MaxProcesses,
+		nice:         cfg.Nice,
+	}
+	if cfg.Nice < -20 || cfg.Nice > 19 {
+		return nil, fmt.Errorf("invalid nice value %d: expected -20 to 19", cfg.Nice)
+	}
+	if cfg.Memory != "" {
+		size, err := parseSize(cfg.Memory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory limit: %w", err)
+		}
+		l.memory = size
+	}
+	if cfg.IONice != "" {
+		prio, err := parseIONice(cfg.IONice)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ionice: %w", err)
+		}
+		l.ioPriority = prio
+	}
+
+	if err := checkLimitsSupported(l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// stricterLimits combines the limits of a and b, keeping the stricter value
+// of every limit set in either. I/O priorities are not comparable, the one of
+// a is kept when both are set.
+func stricterLimits(a, b config.LimitsConfig) config.LimitsConfig {
+	a.CPUSeconds = minLimit(a.CPUSeconds, b.CPUSeconds)
+	a.OpenFiles = minLimit(a.OpenFiles, b.OpenFiles)
+	a.MaxProcesses = minLimit(a.MaxProcesses, b.MaxProcesses)
+	if a.Nice == 0 || (b.Nice != 0 && b.Nice > a.Nice) {
+		a.Nice = b.Nice
+	}
+	if a.Memory == "" {
+		a.Memory = b.Memory
+	} else if b.Memory != "" {
+		sizeA, errA := parseSize(a.Memory)
+		sizeB, errB := parseSize(b.Memory)
+		// an invalid size is kept to be reported
+		if errB != nil || (errA == nil && sizeB < sizeA) {
+			a.Memory = b.Memory
+		}
+	}
+	if a.IONice == "" {
+		a.IONice = b.IONice
+	}
+	return a
+}
+
+// minLimit returns the smaller of two limits, zero meaning no limit.
+func minLimit(a, b uint64) uint64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// parseSize parses a byte count with an optional K, M, G or T suffix (powers of 1024).
+func parseSize(s string) (uint64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+
+	multiplier := uint64(1)
+	if n := len(value); n > 0 {
+		switch value[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			value = value[:n-1]
+		}
+	}
+
+	n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	if n > ^uint64(0)/multiplier {
+		return 0, fmt.Errorf("size '%s' is too large", s)
+	}
+	return n * multiplier, nil
+}
+
+// parseIONice parses an I/O priority given as idle, best-effort[:level] or realtime[:level].
+func parseIONice(s string) (int, error) {
+	name, levelPart, hasLevel := strings.Cut(strings.TrimSpace(s), ":")
+
+	var class int
+	switch strings.ToLower(name) {
+	case "idle":
+		class = ioClassIdle
+	case "best-effort":
+		class = ioClassBestEffort
+	case "realtime":
+		class = ioClassRealtime
+	default:
+		return 0, fmt.Errorf("unknown class '%s': expected idle, best-effort or realtime", name)
+	}
+
+	level := 4
+	if hasLevel {
+		if class == ioClassIdle {
+			return 0, fmt.Errorf("the idle class takes no level")
+		}
+		n, err := strconv.Atoi(levelPart)
+		if err != nil || n < 0 || n > 7 {
+			return 0, fmt.Errorf("invalid level '%s': expected 0 to 7", levelPart)
+		}
+		level = n
+	}
+	if class == ioClassIdle {
+		level = 0
+	}
+	return class<<ioClassShift | level, nil
+}
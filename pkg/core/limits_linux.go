@@ -0,0 +1,71 @@
+package core
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	rlimitNproc    = 6
+	ioprioWhoProc  = 1
+	prioWhoProcess = 0
+)
+
+// checkLimitsSupported reports whether l can be applied on this platform.
+func checkLimitsSupported(l *resourceLimits) error {
+	return nil
+}
+
+// applyLimits applies l to the process pid. Limits that are higher than the
+// current hard limit are capped to it, as raising it needs privileges.
+func applyLimits(pid int, l *resourceLimits) error {
+	rlimits := []struct {
+		resource int
+		name     string
+		value    uint64
+	}{
+		{syscall.RLIMIT_CPU, "cpu_seconds", l.cpuSeconds},
+		{syscall.RLIMIT_AS, "memory", l.memory},
+		{syscall.RLIMIT_NOFILE, "open_files", l.openFiles},
+		{rlimitNproc, "max_processes", l.maxProcesses},
+	}
+	for _, r := range rlimits {
+		if r.value == 0 {
+			continue
+		}
+		if err := setProcessLimit(pid, r.resource, r.value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", r.name, err)
+		}
+	}
+
+	if l.nice != 0 {
+		if err := syscall.Setpriority(prioWhoProcess, pid, l.nice); err != nil {
+			return fmt.Errorf("failed to set nice: %w", err)
+		}
+	}
+	if l.ioPriority != 0 {
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProc, uintptr(pid), uintptr(l.ioPriority)); errno != 0 {
+			return fmt.Errorf("failed to set ionice: %w", errno)
+		}
+	}
+	return nil
+}
+
+func setProcessLimit(pid, resource int, value uint64) error {
+	var current syscall.Rlimit
+	if err := prlimit(pid, resource, nil, &current); err != nil {
+		return err
+	}
+	limit := syscall.Rlimit{Cur: min(value, current.Max), Max: min(value, current.Max)}
+	return prlimit(pid, resource, &limit, nil)
+}
+
+func prlimit(pid, resource int, newLimit, oldLimit *syscall.Rlimit) error {
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource),
+		uintptr(unsafe.Pointer(newLimit)), uintptr(unsafe.Pointer(oldLimit)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package core
+
+import "fmt"
+
+// checkLimitsSupported reports whether l can be applied on this platform.
+// Only nice can be set on another process outside of Linux.
+func checkLimitsSupported(l *resourceLimits) error {
+	if l.cpuSeconds != 0 || l.memory != 0 || l.openFiles != 0 || l.maxProcesses != 0 || l.ioPriority != 0 {
+		return fmt.Errorf("resource limits other than nice are only supported on Linux")
+	}
+	return nil
+}
+
+// applyLimits applies l to the process pid.
+func applyLimits(pid int, l *resourceLimits) error {
+	if l.nice != 0 {
+		if err := setNice(pid, l.nice); err != nil {
+			return fmt.Errorf("failed to set nice: %w", err)
+		}
+	}
+	return nil
+}
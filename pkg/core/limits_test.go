@@ -0,0 +1,127 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"512K", 512 << 10, false},
+		{"512M", 512 << 20, false},
+		{"2G", 2 << 30, false},
+		{"2GiB", 2 << 30, false},
+		{"1gb", 1 << 30, false},
+		{"", 0, true},
+		{"0", 0, true},
+		{"M", 0, true},
+		{"-1M", 0, true},
+		{"1.5G", 0, true},
+		{"99999999999T", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseIONice(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"idle", ioClassIdle << ioClassShift, false},
+		{"best-effort", ioClassBestEffort<<ioClassShift | 4, false},
+		{"best-effort:7", ioClassBestEffort<<ioClassShift | 7, false},
+		{"realtime:0", ioClassRealtime << ioClassShift, false},
+		{"idle:3", 0, true},
+		{"best-effort:8", 0, true},
+		{"fast", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseIONice(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseIONice(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseIONice(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResolveLimits(t *testing.T) {
+	l, err := resolveLimits(config.LimitsConfig{})
+	if err != nil || l != nil {
+		t.Errorf("expected no limits for empty config, got %v, %v", l, err)
+	}
+
+	if _, err := resolveLimits(config.LimitsConfig{Nice: 20}); err == nil {
+		t.Error("expected error for nice out of range")
+	}
+	if _, err := resolveLimits(config.LimitsConfig{Memory: "lots"}); err == nil {
+		t.Error("expected error for invalid memory")
+	}
+
+	l, err = resolveLimits(config.LimitsConfig{Nice: 5})
+	if err != nil {
+		t.Fatalf("resolveLimits failed: %v", err)
+	}
+	if l.nice != 5 {
+		t.Errorf("expected nice 5, got %d", l.nice)
+	}
+}
+
+func TestExecute_AppliesLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only supported on Linux")
+	}
+
+	var out bytes.Buffer
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			// the limits are set before the command executes
+			Run:    `ulimit -n; nice; [ ! -e /proc/$$/fd/3 ] || echo leaked; exit "$1"`,
+			Limits: config.LimitsConfig{OpenFiles: 64, Nice: 5},
+		},
+	}
+
+	err := execute(context.Background(), ctx, []string{"4"}, runOptions{stdout: &out, stderr: &out})
+	if exitCode(err) != 4 {
+		t.Fatalf("expected the exit code of the command, got %v: %s", err, out.String())
+	}
+	if got := out.String(); got != "64\n5\n" {
+		t.Errorf("expected open files limit 64 and nice 5, got %q", got)
+	}
+}
+
+func TestStricterLimits(t *testing.T) {
+	stage := config.LimitsConfig{CPUSeconds: 10, Memory: "1G", Nice: 5, IONice: "idle"}
+	pipeline := config.LimitsConfig{CPUSeconds: 60, Memory: "512M", OpenFiles: 64, Nice: 10, IONice: "best-effort"}
+
+	got := stricterLimits(stage, pipeline)
+	want := config.LimitsConfig{CPUSeconds: 10, Memory: "512M", OpenFiles: 64, Nice: 10, IONice: "idle"}
+	if got != want {
+		t.Errorf("stricterLimits() = %+v, want %+v", got, want)
+	}
+	if got := stricterLimits(config.LimitsConfig{}, pipeline); got != pipeline {
+		t.Errorf("expected the limits of the pipeline, got %+v", got)
+	}
+}
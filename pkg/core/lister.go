@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/brsyuksel/shellican/pkg/config"
@@ -33,7 +34,7 @@ func ListCollections() error {
 	configs := make(map[string]*config.CollectionConfig)
 
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
 			names = append(names, entry.Name())
 			cfg, _ := config.LoadCollectionConfig(filepath.Join(rootDir, entry.Name()))
 			configs[entry.Name()] = cfg
@@ -86,6 +87,9 @@ func ListRunnables(collectionName string) error {
 		if runCfg != nil && runCfg.Help != "" {
 			desc = runCfg.Help
 		}
+		if runCfg != nil && !supportsPlatform(runCfg, currentPlatform) {
+			desc = fmt.Sprintf("[unsupported, %s only] %s", strings.Join(runCfg.Platforms, ", "), desc)
+		}
 
 		_, _ = fmt.Fprintf(w, "%s\t%s\n", name, desc)
 	}
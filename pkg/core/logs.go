@@ -0,0 +1,178 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+const (
+	// defaultLogKeep is the number of run logs kept per runnable when not configured.
+	defaultLogKeep = 20
+	// logTimeFormat names log files so that they sort chronologically.
+	logTimeFormat = "20060102-150405.000000"
+)
+
+// runLogDir resolves the directory holding the logs of a runnable.
+// A configured dir is relative to the runnable directory.
+func runLogDir(collection, runnable, runnablePath string, cfg config.LogConfig) (string, error) {
+	if cfg.Dir != "" {
+		if filepath.IsAbs(cfg.Dir) {
+			return cfg.Dir, nil
+		}
+		return filepath.Join(runnablePath, cfg.Dir), nil
+	}
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "logs", collection, runnable), nil
+}
+
+// openRunLog creates a new timestamped log file for a run and prunes old ones,
+// reporting pruning failures as warnings to w.
+func openRunLog(ctx *ExecutionContext, w io.Writer) (*os.File, error) {
+	dir, err := runLogDir(ctx.Collection, ctx.Runnable, ctx.RunnablePath, ctx.Config.Log)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log dir: %w", err)
+	}
+
+	name := time.Now().Format(logTimeFormat) + ".log"
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log file: %w", err)
+	}
+
+	if err := pruneRunLogs(dir, ctx.Config.Log); err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: failed to prune run logs: %v\n", err)
+	}
+	return f, nil
+}
+
+// listRunLogs returns the log files in dir, oldest first.
+func listRunLogs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list logs: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".log") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// pruneRunLogs enforces the retention limits of cfg on dir.
+func pruneRunLogs(dir string, cfg config.LogConfig) error {
+	files, err := listRunLogs(dir)
+	if err != nil {
+		return err
+	}
+
+	keep := cfg.Keep
+	if keep <= 0 {
+		keep = defaultLogKeep
+	}
+
+	for i, file := range files {
+		expired := len(files)-i > keep
+		if !expired && cfg.MaxAge > 0 {
+			if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) > cfg.MaxAge {
+				expired = true
+			}
+		}
+		// never remove the newest file, it belongs to the current run
+		if expired && i < len(files)-1 {
+			if err := os.Remove(file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ShowLogs prints the most recent log of a runnable, or the list of its logs.
+// With follow, it keeps printing output appended to the latest log.
+func ShowLogs(collectionName, runnableName string, list, follow bool) error {
+	rootDir, err := getRoot()
+	if err != nil {
+		return err
+	}
+	runnablePath := filepath.Join(rootDir, collectionName, runnableName)
+
+	cfg, err := config.LoadRunnableConfig(runnablePath)
+	if err != nil {
+		return fmt.Errorf("failed to load runnable config: %w", err)
+	}
+	if cfg == nil {
+		return fmt.Errorf("runnable '%s' not found in collection '%s'", runnableName, collectionName)
+	}
+
+	dir, err := runLogDir(collectionName, runnableName, runnablePath, cfg.Log)
+	if err != nil {
+		return err
+	}
+	files, err := listRunLogs(dir)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println("No logs found.")
+		return nil
+	}
+
+	if list {
+		for _, file := range files {
+			fmt.Println(file)
+		}
+		return nil
+	}
+
+	return printLog(os.Stdout, files[len(files)-1], follow, nil)
+}
+
+// printLog copies the log at path to w. With follow, it keeps printing
+// output appended to it until interrupted or, when running is set, until
+// running reports false and the rest of the log has been printed.
+func printLog(w io.Writer, path string, follow bool, running func() bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("failed to read log: %w", err)
+	}
+	if !follow {
+		return nil
+	}
+
+	for {
+		time.Sleep(500 * time.Millisecond)
+		// checked before copying so output written before the exit is printed
+		exited := running != nil && !running()
+		if _, err := io.Copy(w, f); err != nil {
+			return fmt.Errorf("failed to read log: %w", err)
+		}
+		if exited {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestExecuteContext_Log(t *testing.T) {
+	tempDir := t.TempDir()
+
+	ctx := &ExecutionContext{
+		RunnablePath: tempDir,
+		Config: &config.RunnableConfig{
+			Before: "echo before",
+			Run:    "echo out; echo err >&2",
+			Log:    config.LogConfig{Enabled: true, Dir: "logs", Keep: 2},
+		},
+		Environments: map[string]string{},
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := ExecuteContext(ctx, nil); err != nil {
+			t.Fatalf("ExecuteContext failed: %v", err)
+		}
+	}
+
+	files, err := listRunLogs(filepath.Join(tempDir, "logs"))
+	if err != nil {
+		t.Fatalf("listRunLogs failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 retained logs, got %d", len(files))
+	}
+
+	content, err := os.ReadFile(files[len(files)-1])
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, want := range []string{"before", "out", "err"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected log to contain %q, got %q", want, content)
+		}
+	}
+}
+
+func TestShowLogs(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_HOME", tempDir)
+
+	if err := CreateCollection("col1"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	if err := CreateRunnable("col1", "run1"); err != nil {
+		t.Fatalf("setup run failed: %v", err)
+	}
+
+	if err := ShowLogs("col1", "run1", false, false); err != nil {
+		t.Errorf("ShowLogs failed without logs: %v", err)
+	}
+
+	ctx, err := ResolveCommand("col1", []string{"run1"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	ctx.Config.Log.Enabled = true
+	if err := ExecuteContext(ctx, nil); err != nil {
+		t.Fatalf("ExecuteContext failed: %v", err)
+	}
+
+	dir := filepath.Join(tempDir, ".shellican", ".state", "logs", "col1", "run1")
+	if files, _ := listRunLogs(dir); len(files) != 1 {
+		t.Errorf("Expected one log under the state dir, got %v", files)
+	}
+	if err := ShowLogs("col1", "run1", false, false); err != nil {
+		t.Errorf("ShowLogs failed: %v", err)
+	}
+
+	if err := ShowLogs("col1", "missing", false, false); err == nil {
+		t.Error("Expected error for missing runnable")
+	}
+}
@@ -0,0 +1,264 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// MatrixRun is the outcome of one combination of a matrix run.
+type MatrixRun struct {
+	Values   map[string]string
+	ExitCode int
+	Duration time.Duration
+	Err      error
+	// UpToDate is set when the combination was skipped because its sources did not change.
+	UpToDate bool
+}
+
+// ParseMatrix builds matrix values from KEY=a,b,c assignments.
+func ParseMatrix(assignments []string) (map[string][]string, error) {
+	matrix := make(map[string][]string)
+	for _, assignment := range assignments {
+		name, values, ok := strings.Cut(assignment, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || values == "" {
+			return nil, fmt.Errorf("invalid matrix '%s': expected KEY=a,b,c", assignment)
+		}
+		for _, value := range strings.Split(values, ",") {
+			matrix[name] = append(matrix[name], strings.TrimSpace(value))
+		}
+	}
+	return matrix, nil
+}
+
+// HasMatrix reports whether running ctx with the given matrix overrides expands into a matrix.
+func HasMatrix(ctx *ExecutionContext, overrides map[string][]string) bool {
+	return len(ctx.Config.Matrix) > 0 || len(overrides) > 0
+}
+
+// mergeMatrix returns the matrix of ctx with overrides replacing its values.
+func mergeMatrix(ctx *ExecutionContext, overrides map[string][]string) (map[string][]string, error) {
+	matrix := maps.Clone(ctx.Config.Matrix)
+	if matrix == nil {
+		matrix = make(map[string][]string)
+	}
+	maps.Copy(matrix, overrides)
+	for key, values := range matrix {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("matrix key '%s' has no values", key)
+		}
+	}
+	return matrix, nil
+}
+
+// expandMatrix returns every combination of the values of matrix, varying the
+// last key, in name order, first.
+func expandMatrix(matrix map[string][]string) []map[string]string {
+	keys := slices.Sorted(maps.Keys(matrix))
+	combinations := []map[string]string{{}}
+	for _, key := range keys {
+		var expanded []map[string]string
+		for _, combination := range combinations {
+			for _, value := range matrix[key] {
+				next := maps.Clone(combination)
+				next[key] = value
+				expanded = append(expanded, next)
+			}
+		}
+		combinations = expanded
+	}
+	return combinations
+}
+
+// matrixValues returns the matrix values ctx runs with, if any.
+func matrixValues(ctx *ExecutionContext) map[string]string {
+	values := make(map[string]string)
+	for name, source := range ctx.EnvironmentSources {
+		if source == EnvSourceMatrix {
+			values[name] = ctx.Environments[name]
+		}
+	}
+	return values
+}
+
+// matrixLabel describes a combination as KEY=value pairs in name order.
+func matrixLabel(values map[string]string) string {
+	pairs := make([]string, 0, len(values))
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		pairs = append(pairs, key+"="+values[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// RunMatrix runs ctx once per combination of its matrix, with overrides
+// replacing the values of the runnable, up to jobs at once.
+func RunMatrix(ctx *ExecutionContext, args []string, overrides map[string][]string, jobs int) ([]MatrixRun, error) {
+	start := time.Now()
+	runs, err := runMatrix(context.Background(), ctx, args, overrides, jobs, defaultRunOptions())
+	notifyRun(ctx, nil, time.Since(start), err, os.Stdout, os.Stderr)
+	return runs, err
+}
+
+// runMatrix runs every combination with its values set as environment
+// variables, prefixing output lines with the combination, then prints a grid
+// of the results.
+func runMatrix(c context.Context, ctx *ExecutionContext, args []string, overrides map[string][]string, jobs int, opts runOptions) ([]MatrixRun, error) {
+	matrix, err := mergeMatrix(ctx, overrides)
+	if err != nil {
+		return nil, err
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	// combinations run without a terminal, so confirmations and missing
+	// variables are asked for upfront
+	if err := confirmRun(ctx, opts); err != nil {
+		return nil, err
+	}
+	base := *ctx
+	base.AssumeYes = true
+	base.Prompts = slices.DeleteFunc(slices.Clone(ctx.Prompts), func(env config.EnvRequirement) bool {
+		_, ok := matrix[env.Name]
+		return ok
+	})
+	prompted, err := promptMissingEnv(&base, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := &lockedWriter{w: opts.stdout}
+	stderr := &lockedWriter{w: opts.stderr}
+	combinations := expandMatrix(matrix)
+	runs := make([]MatrixRun, len(combinations))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, values := range combinations {
+		runCtx := *prompted
+		runCtx.Environments = maps.Clone(prompted.Environments)
+		runCtx.EnvironmentSources = maps.Clone(prompted.EnvironmentSources)
+		if runCtx.Environments == nil {
+			runCtx.Environments = make(map[string]string)
+		}
+		if runCtx.EnvironmentSources == nil {
+			runCtx.EnvironmentSources = make(map[string]string)
+		}
+		for name, value := range values {
+			runCtx.Environments[name] = value
+			runCtx.EnvironmentSources[name] = EnvSourceMatrix
+		}
+
+		prefix := "[" + matrixLabel(values) + "] "
+		runOpts := opts
+		runOpts.stdin = nil
+		out := &prefixWriter{w: stdout, prefix: prefix}
+		errOut := &prefixWriter{w: stderr, prefix: prefix}
+		runOpts.stdout = out
+		runOpts.stderr = errOut
+		runOpts.result = &Result{}
+		runOpts.events = nil
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ctx *ExecutionContext, runOpts runOptions) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			start := time.Now()
+			err := executeRecorded(c, ctx, args, runOpts)
+			out.Flush()
+			errOut.Flush()
+			runs[i] = MatrixRun{Values: values, ExitCode: exitCode(err), Duration: time.Since(start), Err: err, UpToDate: runOpts.result.UpToDate}
+		}(i, &runCtx, runOpts)
+	}
+	wg.Wait()
+
+	if err := printMatrixGrid(opts.stdout, runs); err != nil {
+		return runs, err
+	}
+
+	failed := 0
+	for _, run := range runs {
+		if run.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return runs, fmt.Errorf("%d of %d matrix runs failed", failed, len(runs))
+	}
+	return runs, nil
+}
+
+// printMatrixGrid prints a row per combination with its values and outcome.
+func printMatrixGrid(out io.Writer, runs []MatrixRun) error {
+	if len(runs) == 0 {
+		return nil
+	}
+	keys := slices.Sorted(maps.Keys(runs[0].Values))
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "\n%s\tRESULT\tDURATION\n", strings.Join(keys, "\t"))
+	for _, run := range runs {
+		cells := make([]string, 0, len(keys))
+		for _, key := range keys {
+			cells = append(cells, run.Values[key])
+		}
+		result := "pass"
+		switch {
+		case run.Err != nil:
+			result = fmt.Sprintf("fail (exit %d)", run.ExitCode)
+		case run.UpToDate:
+			result = "skipped"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", strings.Join(cells, "\t"), result, run.Duration.Round(time.Millisecond))
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	return nil
+}
+
+// prefixWriter writes every line written through it to w with a prefix.
+type prefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := p.w.Write(append([]byte(p.prefix), p.buf[:i+1]...)); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes the last line when it does not end with a newline.
+func (p *prefixWriter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buf) > 0 {
+		_, _ = p.w.Write(append(append([]byte(p.prefix), p.buf...), '\n'))
+		p.buf = nil
+	}
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestParseMatrix(t *testing.T) {
+	matrix, err := ParseMatrix([]string{"GO=1.21, 1.22", "OS=linux"})
+	if err != nil {
+		t.Fatalf("ParseMatrix failed: %v", err)
+	}
+	if !slices.Equal(matrix["GO"], []string{"1.21", "1.22"}) || !slices.Equal(matrix["OS"], []string{"linux"}) {
+		t.Errorf("unexpected matrix %v", matrix)
+	}
+
+	for _, invalid := range []string{"GO", "=a", "GO="} {
+		if _, err := ParseMatrix([]string{invalid}); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestExpandMatrix(t *testing.T) {
+	combinations := expandMatrix(map[string][]string{"OS": {"linux", "darwin"}, "ARCH": {"amd64", "arm64"}})
+	var labels []string
+	for _, c := range combinations {
+		labels = append(labels, matrixLabel(c))
+	}
+	want := []string{
+		"ARCH=amd64 OS=linux",
+		"ARCH=amd64 OS=darwin",
+		"ARCH=arm64 OS=linux",
+		"ARCH=arm64 OS=darwin",
+	}
+	if !slices.Equal(labels, want) {
+		t.Errorf("expected %q, got %q", want, labels)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &prefixWriter{w: &out, prefix: "[a] "}
+	_, _ = w.Write([]byte("one\ntw"))
+	_, _ = w.Write([]byte("o\nthree"))
+	w.Flush()
+	if out.String() != "[a] one\n[a] two\n[a] three\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestRunMatrix(t *testing.T) {
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Run:    `echo "$GO/$OS $1"; [ "$GO" != 1.21 ] || exit 3`,
+			Matrix: map[string][]string{"GO": {"1.21", "1.22"}, "OS": {"linux"}},
+		},
+		Environments: map[string]string{"GO": "ignored"},
+	}
+
+	var stdout bytes.Buffer
+	opts := runOptions{stdout: &stdout, stderr: &stdout}
+	runs, err := runMatrix(context.Background(), ctx, []string{"x"}, map[string][]string{"OS": {"linux", "darwin"}}, 4, opts)
+	if err == nil || !strings.Contains(err.Error(), "2 of 4 matrix runs failed") {
+		t.Fatalf("expected 2 failed runs, got %v", err)
+	}
+
+	out := stdout.String()
+	for _, want := range []string{"[GO=1.21 OS=darwin] 1.21/darwin x\n", "[GO=1.22 OS=linux] 1.22/linux x\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got %q", want, out)
+		}
+	}
+	grid := out[strings.Index(out, "\nGO"):]
+	if !strings.Contains(grid, "1.21  darwin  fail (exit 3)") || !strings.Contains(grid, "1.22  linux   pass") {
+		t.Errorf("unexpected grid %q", grid)
+	}
+
+	if len(runs) != 4 || runs[0].Values["OS"] != "linux" || runs[0].ExitCode != 3 || runs[3].Err != nil {
+		t.Errorf("unexpected runs %+v", runs)
+	}
+	if ctx.Environments["GO"] != "ignored" {
+		t.Error("expected the context not to be modified")
+	}
+}
+
+func TestRunMatrix_NoValues(t *testing.T) {
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config:       &config.RunnableConfig{Run: "true", Matrix: map[string][]string{"GO": nil}},
+	}
+	if _, err := runMatrix(context.Background(), ctx, nil, nil, 1, runOptions{stdout: &bytes.Buffer{}, stderr: &bytes.Buffer{}}); err == nil {
+		t.Error("expected error for a key without values")
+	}
+}
+
+func TestRunMatrix_FingerprintPerCombination(t *testing.T) {
+	t.Setenv("SHELLICAN_HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "src"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &ExecutionContext{
+		Collection:   "col",
+		Runnable:     "m",
+		RunnablePath: dir,
+		Config: &config.RunnableConfig{
+			Run:     `echo "$V" >> runs`,
+			Sources: []string{"src"},
+			Matrix:  map[string][]string{"V": {"a", "b"}},
+		},
+	}
+
+	for _, want := range []string{"pass", "skipped"} {
+		var stdout bytes.Buffer
+		runs, err := runMatrix(context.Background(), ctx, nil, nil, 1, runOptions{stdout: &stdout, stderr: &stdout})
+		if err != nil {
+			t.Fatalf("runMatrix failed: %v", err)
+		}
+		for _, run := range runs {
+			if run.UpToDate != (want == "skipped") {
+				t.Errorf("unexpected run %+v", run)
+			}
+		}
+		if n := strings.Count(stdout.String(), "  "+want+"  "); n != 2 {
+			t.Errorf("expected 2 combinations to be %s, got:\n%s", want, stdout.String())
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "runs"))
+	if string(data) != "a\nb\n" {
+		t.Errorf("expected each combination to run once, got %q", data)
+	}
+}
+
+func TestCheckRequirements_MatrixVariables(t *testing.T) {
+	writeTestRunnables(t, map[string]string{
+		"m": "run: 'echo \"$TARGET $ARCH\"'\nrequires:\n  env: [TARGET, ARCH]\nmatrix:\n  TARGET: [one, two]\n",
+	})
+
+	// TARGET comes from the matrix of the runnable, ARCH from the command line
+	if _, err := ResolveCommand("col", []string{"m"}); err == nil || strings.Contains(err.Error(), "TARGET") || !strings.Contains(err.Error(), "ARCH") {
+		t.Errorf("expected only ARCH to be missing, got %v", err)
+	}
+	ctx, err := ResolveCommandUnchecked("col", []string{"m"}, nil)
+	if err != nil {
+		t.Fatalf("ResolveCommandUnchecked failed: %v", err)
+	}
+	overrides := map[string][]string{"ARCH": {"amd64"}}
+	if err := CheckRequirements(ctx, overrides); err != nil {
+		t.Fatalf("expected the matrix to provide the required variables, got %v", err)
+	}
+
+	var stdout bytes.Buffer
+	if _, err := runMatrix(context.Background(), ctx, nil, overrides, 1, runOptions{stdout: &stdout, stderr: &stdout}); err != nil {
+		t.Fatalf("runMatrix failed: %v", err)
+	}
+	for _, want := range []string{"one amd64", "two amd64"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected %q in output, got:\n%s", want, stdout.String())
+		}
+	}
+}
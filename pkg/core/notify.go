@@ -0,0 +1,220 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// notifyTimeout bounds how long a single notification may take.
+const notifyTimeout = 10 * time.Second
+
+// Notification statuses.
+const (
+	NotifySuccess = "success"
+	NotifyFailure = "failure"
+)
+
+// Notification describes a finished run.
+type Notification struct {
+	Collection string        `json:"collection"`
+	Runnable   string        `json:"runnable"`
+	Status     string        `json:"status"`
+	ExitCode   int           `json:"exit_code"`
+	Duration   time.Duration `json:"-"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Message is a one-line summary of n.
+func (n Notification) Message() string {
+	name := n.Runnable
+	if n.Collection != "" {
+		name = n.Collection + "/" + n.Runnable
+	}
+	if n.Status == NotifySuccess {
+		return fmt.Sprintf("%s succeeded in %s", name, n.Duration.Round(time.Second))
+	}
+	return fmt.Sprintf("%s failed with exit code %d after %s", name, n.ExitCode, n.Duration.Round(time.Second))
+}
+
+// Notifier delivers notifications of finished runs.
+type Notifier interface {
+	Notify(context.Context, Notification) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface.
+type NotifierFunc func(context.Context, Notification) error
+
+// Notify calls f(c, n).
+func (f NotifierFunc) Notify(c context.Context, n Notification) error {
+	return f(c, n)
+}
+
+// shouldNotify reports whether cfg asks for a notification of a run that
+// lasted duration and failed if err is not nil.
+func shouldNotify(cfg config.NotifyConfig, duration time.Duration, err error) (bool, error) {
+	if !cfg.Enabled || duration < cfg.MinDuration {
+		return false, nil
+	}
+	switch cfg.On {
+	case "", "always":
+		return true, nil
+	case NotifySuccess:
+		return err == nil, nil
+	case NotifyFailure:
+		return err != nil, nil
+	default:
+		return false, fmt.Errorf("invalid notify.on '%s': expected success, failure or always", cfg.On)
+	}
+}
+
+// configuredNotifiers returns the notifiers enabled on the runnable of ctx.
+// The bell rings on bell.
+func configuredNotifiers(ctx *ExecutionContext, bell io.Writer) []Notifier {
+	cfg := ctx.Config.Notify
+	var notifiers []Notifier
+	if cfg.Desktop {
+		notifiers = append(notifiers, NotifierFunc(notifyDesktop))
+	}
+	if cfg.Bell {
+		notifiers = append(notifiers, NotifierFunc(func(context.Context, Notification) error {
+			_, err := io.WriteString(bell, "\a")
+			return err
+		}))
+	}
+	if cfg.Command != "" {
+		notifiers = append(notifiers, NotifierFunc(func(c context.Context, n Notification) error {
+			return notifyCommand(c, ctx, cfg.Command, n)
+		}))
+	}
+	if cfg.Webhook != "" {
+		notifiers = append(notifiers, NotifierFunc(func(c context.Context, n Notification) error {
+			return notifyWebhook(c, cfg.Webhook, n)
+		}))
+	}
+	return notifiers
+}
+
+// notifyRun sends the notifications configured on the runnable of ctx, and to
+// extra if not nil, about a run that lasted duration and failed if err is not nil.
+// Failures are reported as warnings to w, the bell rings on bell.
+func notifyRun(ctx *ExecutionContext, extra Notifier, duration time.Duration, err error, w, bell io.Writer) {
+	cfg := ctx.Config.Notify
+	ok, cfgErr := shouldNotify(cfg, duration, err)
+	if cfgErr != nil {
+		_, _ = fmt.Fprintf(w, "Warning: %v\n", cfgErr)
+	}
+	if !ok {
+		return
+	}
+
+	notifiers := configuredNotifiers(ctx, bell)
+	if extra != nil {
+		notifiers = append(notifiers, extra)
+	}
+	n := Notification{
+		Collection: ctx.Collection,
+		Runnable:   runnableName(ctx),
+		Status:     NotifySuccess,
+		ExitCode:   exitCode(err),
+		Duration:   duration,
+	}
+	if err != nil {
+		n.Status = NotifyFailure
+		n.Error = err.Error()
+	}
+	sendNotifications(notifiers, n, w)
+}
+
+// sendNotifications delivers n through every notifier, reporting failures as warnings to w.
+func sendNotifications(notifiers []Notifier, n Notification, w io.Writer) {
+	for _, notifier := range notifiers {
+		c, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		err := notifier.Notify(c, n)
+		cancel()
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: failed to send notification: %v\n", err)
+		}
+	}
+}
+
+// notifyDesktop shows n with the notification tool of the desktop.
+func notifyDesktop(c context.Context, n Notification) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title \"shellican\"", strconv.Quote(n.Message()))
+		cmd = exec.CommandContext(c, "osascript", "-e", script)
+	default:
+		cmd = exec.CommandContext(c, "notify-send", "shellican", n.Message())
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", cmd.Args[0], err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// notifyCommand runs a shell command like the commands of the runnable of ctx,
+// in its environment, sandbox and limits, with the details of n added to the
+// environment.
+func notifyCommand(c context.Context, ctx *ExecutionContext, command string, n Notification) error {
+	limits, err := resolveLimits(ctx.Config.Limits)
+	if err != nil {
+		return fmt.Errorf("invalid limits: %w", err)
+	}
+	sandbox, err := resolveSandbox(ctx)
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	opts := runOptions{stdout: &output, stderr: &output, processGroup: true, limits: limits, sandbox: sandbox}
+	env := append(buildEnv(ctx),
+		"SHELLICAN_NOTIFY_COLLECTION="+n.Collection,
+		"SHELLICAN_NOTIFY_RUNNABLE="+n.Runnable,
+		"SHELLICAN_NOTIFY_STATUS="+n.Status,
+		"SHELLICAN_NOTIFY_EXIT_CODE="+strconv.Itoa(n.ExitCode),
+		"SHELLICAN_NOTIFY_DURATION="+n.Duration.Round(time.Millisecond).String(),
+		"SHELLICAN_NOTIFY_MESSAGE="+n.Message(),
+	)
+	if err := runShell(c, command, nil, env, ctx.RunnablePath, opts); err != nil {
+		return fmt.Errorf("notify command failed: %w: %s", err, bytes.TrimSpace(output.Bytes()))
+	}
+	return nil
+}
+
+// notifyWebhook posts n as JSON to url.
+func notifyWebhook(c context.Context, url string, n Notification) error {
+	body, err := json.Marshal(struct {
+		Notification
+		DurationMs int64  `json:"duration_ms"`
+		Message    string `json:"message"`
+	}{n, n.Duration.Milliseconds(), n.Message()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(c, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+	return nil
+}
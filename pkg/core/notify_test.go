@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestShouldNotify(t *testing.T) {
+	failure := os.ErrNotExist
+	tests := []struct {
+		cfg      config.NotifyConfig
+		duration time.Duration
+		err      error
+		want     bool
+	}{
+		{config.NotifyConfig{}, time.Minute, nil, false},
+		{config.NotifyConfig{Enabled: true}, 0, failure, true},
+		{config.NotifyConfig{Enabled: true, On: "always"}, 0, nil, true},
+		{config.NotifyConfig{Enabled: true, On: "success"}, 0, failure, false},
+		{config.NotifyConfig{Enabled: true, On: "failure"}, 0, failure, true},
+		{config.NotifyConfig{Enabled: true, On: "failure"}, 0, nil, false},
+		{config.NotifyConfig{Enabled: true, MinDuration: time.Minute}, time.Second, nil, false},
+		{config.NotifyConfig{Enabled: true, MinDuration: time.Minute}, time.Hour, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := shouldNotify(tt.cfg, tt.duration, tt.err)
+		if err != nil || got != tt.want {
+			t.Errorf("shouldNotify(%+v, %s, %v) = %v, %v, want %v", tt.cfg, tt.duration, tt.err, got, err, tt.want)
+		}
+	}
+
+	if _, err := shouldNotify(config.NotifyConfig{Enabled: true, On: "sometimes"}, 0, nil); err == nil {
+		t.Error("expected error for an invalid on value")
+	}
+}
+
+func TestNotifyWebhook(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	received := make(chan map[string]any, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	ctx := &ExecutionContext{
+		Collection:   "col",
+		Runnable:     "build",
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Run:    "exit 3",
+			Notify: config.NotifyConfig{Enabled: true, On: "failure", Webhook: srv.URL},
+		},
+	}
+	var stdout bytes.Buffer
+	if _, err := (&Executor{Stdout: &stdout, SkipHistory: true}).Run(context.Background(), ctx, nil); err == nil {
+		t.Fatal("expected run to fail")
+	}
+
+	select {
+	case payload := <-received:
+		if payload["collection"] != "col" || payload["runnable"] != "build" || payload["status"] != "failure" || payload["exit_code"] != float64(3) {
+			t.Errorf("unexpected payload %v", payload)
+		}
+		if msg, _ := payload["message"].(string); !strings.HasPrefix(msg, "col/build failed with exit code 3") {
+			t.Errorf("unexpected message %q", msg)
+		}
+	default:
+		t.Fatal("expected the webhook to be called")
+	}
+	if strings.Contains(stdout.String(), "Warning") {
+		t.Errorf("unexpected warning %q", stdout.String())
+	}
+
+	status.Store(http.StatusInternalServerError)
+	stdout.Reset()
+	_, _ = (&Executor{Stdout: &stdout, SkipHistory: true}).Run(context.Background(), ctx, nil)
+	if !strings.Contains(stdout.String(), "Warning: failed to send notification: webhook returned status 500") {
+		t.Errorf("expected webhook failure warning, got %q", stdout.String())
+	}
+}
+
+func TestNotifyRun_BellCommandAndNotifier(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "notified")
+	ctx := &ExecutionContext{
+		Collection:   "col",
+		Runnable:     "build",
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Run: "true",
+			Notify: config.NotifyConfig{
+				Enabled: true,
+				Bell:    true,
+				Command: `echo "$SHELLICAN_NOTIFY_STATUS $SHELLICAN_NOTIFY_RUNNABLE" > ` + shellQuote(out),
+			},
+		},
+	}
+
+	var got []Notification
+	var stderr bytes.Buffer
+	e := &Executor{
+		Stderr:      &stderr,
+		SkipHistory: true,
+		Notifier: NotifierFunc(func(_ context.Context, n Notification) error {
+			got = append(got, n)
+			return nil
+		}),
+	}
+	if _, err := e.Run(context.Background(), ctx, nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if stderr.String() != "\a" {
+		t.Errorf("expected a bell, got %q", stderr.String())
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || string(data) != "success build\n" {
+		t.Errorf("expected notify command to run, got %q, %v", data, err)
+	}
+	if len(got) != 1 || got[0].Status != NotifySuccess || got[0].Collection != "col" {
+		t.Errorf("unexpected notifications %+v", got)
+	}
+
+	ctx.Config.Notify.MinDuration = time.Hour
+	got = nil
+	if _, err := e.Run(context.Background(), ctx, nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no notification below min_duration, got %+v", got)
+	}
+}
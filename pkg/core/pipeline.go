@@ -0,0 +1,193 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// StageResult is the outcome of one stage of a pipeline.
+type StageResult struct {
+	Name     string
+	ExitCode int
+	Err      error
+}
+
+// ResolvePipeline resolves pipeline stages given as <collection>/<runnable>,
+// or as <runnable> of defaultCollection.
+func ResolvePipeline(defaultCollection string, refs []string) ([]*ExecutionContext, error) {
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("pipeline has no stages")
+	}
+
+	stages := make([]*ExecutionContext, 0, len(refs))
+	for _, ref := range refs {
+		collection, runnable, ok := strings.Cut(ref, "/")
+		if !ok {
+			collection, runnable = defaultCollection, ref
+		}
+		if collection == "" || runnable == "" {
+			return nil, fmt.Errorf("invalid pipeline stage '%s': expected <collection>/<runnable>", ref)
+		}
+
+		ctx, err := ResolveCommand(collection, []string{runnable})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve pipeline stage '%s': %w", ref, err)
+		}
+		if len(ctx.Config.Pipeline) > 0 {
+			return nil, fmt.Errorf("pipeline stage '%s' is itself a pipeline", ref)
+		}
+		stages = append(stages, ctx)
+	}
+	return stages, nil
+}
+
+// RunPipeline runs stages on the standard streams, the first one reading stdin
+// and the last one writing stdout. args are passed to the first stage.
+func RunPipeline(stages []*ExecutionContext, args []string, assumeYes bool) ([]StageResult, error) {
+	for _, stage := range stages {
+		stage.AssumeYes = stage.AssumeYes || assumeYes
+	}
+	return runPipeline(context.Background(), stages, args, defaultRunOptions())
+}
+
+// runPipeline runs every stage at once, connecting the stdout of the run command
+// of each stage to the stdin of the next one. Each stage keeps its own environment and working
+// directory. Following pipefail, the error is the one of the last failed stage.
+func runPipeline(c context.Context, stages []*ExecutionContext, args []string, opts runOptions) ([]StageResult, error) {
+	// stages after the first one read the pipe, so confirmations and missing
+	// variables are asked for upfront
+	stages = slices.Clone(stages)
+	for i, stage := range stages {
+		if err := confirmRun(stage, opts); err != nil {
+			return nil, err
+		}
+		prompted, err := promptMissingEnv(stage, opts)
+		if err != nil {
+			return nil, err
+		}
+		stages[i] = prompted
+	}
+
+	// every stage writes to the same stderr
+	if _, ok := opts.stderr.(*os.File); !ok {
+		opts.stderr = &lockedWriter{w: opts.stderr}
+	}
+
+	results := make([]StageResult, len(stages))
+	var wg sync.WaitGroup
+	stdin := opts.stdin
+	for i, stage := range stages {
+		confirmed := *stage
+		confirmed.AssumeYes = true
+
+		// only the run command of a stage uses the pipes, its hooks read
+		// nothing and write to stderr along with its messages
+		stageOpts := opts
+		stageOpts.stdin = nil
+		stageOpts.stdout = opts.stderr
+		stageOpts.input = stdin
+		stageOpts.output = opts.stdout
+		var pw *io.PipeWriter
+		if i < len(stages)-1 {
+			var pr *io.PipeReader
+			pr, pw = io.Pipe()
+			stageOpts.output = pw
+			stdin = pr
+		}
+		// stages report to their own history entry, not to the result and events of the caller
+		stageOpts.result = nil
+		stageOpts.events = nil
+
+		var stageArgs []string
+		if i == 0 {
+			stageArgs = args
+		}
+
+		wg.Add(1)
+		go func(i int, ctx *ExecutionContext, stageOpts runOptions, pw *io.PipeWriter) {
+			defer wg.Done()
+			err := executeRecorded(c, ctx, stageArgs, stageOpts)
+			// the next stage sees the end of its input, the previous one a broken pipe
+			if pw != nil {
+				_ = pw.Close()
+			}
+			if pr, ok := stageOpts.input.(*io.PipeReader); ok {
+				_ = pr.CloseWithError(io.ErrClosedPipe)
+			}
+			results[i] = StageResult{Name: stageName(ctx), ExitCode: exitCode(err), Err: err}
+		}(i, &confirmed, stageOpts, pw)
+	}
+	wg.Wait()
+
+	var failed *StageResult
+	var status []string
+	for i := range results {
+		if results[i].Err != nil {
+			failed = &results[i]
+		}
+		status = append(status, fmt.Sprintf("%s=%d", results[i].Name, results[i].ExitCode))
+	}
+	_, _ = fmt.Fprintf(opts.stderr, "pipeline: %s\n", strings.Join(status, " | "))
+
+	if failed != nil {
+		return results, fmt.Errorf("pipeline stage %s failed: %w", failed.Name, failed.Err)
+	}
+	return results, nil
+}
+
+// inheritRunSettings returns a copy of stage running under the settings of the
+// pipeline runnable parent: its flags, its sandbox unless the stage has its
+// own, and the stricter of both limits.
+func inheritRunSettings(stage, parent *ExecutionContext) *ExecutionContext {
+	inherited := *stage
+	cfg := *stage.Config
+	inherited.Config = &cfg
+	inherited.AssumeYes = stage.AssumeYes || parent.AssumeYes
+	inherited.Force = stage.Force || parent.Force
+	inherited.Sandbox = stage.Sandbox || parent.Sandbox
+
+	if sandbox := parent.Config.Sandbox; sandbox.Enabled {
+		if !cfg.Sandbox.Enabled {
+			// writable paths are relative to the pipeline, not to the stage
+			cfg.Sandbox = config.SandboxConfig{Enabled: true, Network: cfg.Sandbox.Network}
+			for _, path := range sandbox.Writable {
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(parent.RunnablePath, path)
+				}
+				cfg.Sandbox.Writable = append(cfg.Sandbox.Writable, path)
+			}
+		}
+		if sandbox.Network != nil && !*sandbox.Network {
+			cfg.Sandbox.Network = sandbox.Network
+		}
+	}
+	cfg.Limits = stricterLimits(cfg.Limits, parent.Config.Limits)
+	return &inherited
+}
+
+func stageName(ctx *ExecutionContext) string {
+	if ctx.Collection == "" {
+		return runnableName(ctx)
+	}
+	return ctx.Collection + "/" + runnableName(ctx)
+}
+
+// lockedWriter serializes writes from several commands to w.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestRunnables creates collection col with a runnable per name and config.
+func writeTestRunnables(t *testing.T, runnables map[string]string) string {
+	t.Helper()
+	t.Setenv("SHELLICAN_HOME", t.TempDir())
+	if err := CreateCollection("col"); err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+	dir := ""
+	for name, content := range runnables {
+		if err := CreateRunnable("col", name); err != nil {
+			t.Fatalf("CreateRunnable failed: %v", err)
+		}
+		dir = filepath.Join(os.Getenv("SHELLICAN_HOME"), ".shellican", "col", name)
+		if err := os.WriteFile(filepath.Join(dir, "runnable.yml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Dir(dir)
+}
+
+func TestRunPipeline(t *testing.T) {
+	colDir := writeTestRunnables(t, map[string]string{
+		"gen":   "run: 'printf \"b\\na\\n$1\\n\"'\n",
+		"upper": "run: 'tr a-z A-Z; echo \"$STAGE\" >&2'\nenvironments:\n  STAGE: upper\n",
+		"where": "run: 'sort; basename \"$(pwd)\"'\n",
+	})
+
+	stages, err := ResolvePipeline("", []string{"col/gen", "col/upper", "col/where"})
+	if err != nil {
+		t.Fatalf("ResolvePipeline failed: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	opts := runOptions{stdout: &stdout, stderr: &stderr}
+	results, err := runPipeline(context.Background(), stages, []string{"c"}, opts)
+	if err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+
+	if stdout.String() != "A\nB\nC\nwhere\n" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "upper\n") {
+		t.Errorf("expected stage env in stderr, got %q", stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "pipeline: col/gen=0 | col/upper=0 | col/where=0") {
+		t.Errorf("expected status line, got %q", stderr.String())
+	}
+	if len(results) != 3 || results[1].Name != "col/upper" {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	// each stage is recorded in the history
+	data, _ := os.ReadFile(filepath.Join(filepath.Dir(colDir), ".state", "history.jsonl"))
+	if n := strings.Count(string(data), "\n"); n != 3 {
+		t.Errorf("expected 3 history entries, got %d", n)
+	}
+}
+
+func TestRunPipeline_Pipefail(t *testing.T) {
+	writeTestRunnables(t, map[string]string{
+		"gen":  "run: 'echo a; exit 3'\n",
+		"cat":  "run: 'cat'\n",
+		"fail": "run: 'cat >/dev/null; exit 2'\n",
+	})
+
+	stages, err := ResolvePipeline("col", []string{"gen", "fail", "cat"})
+	if err != nil {
+		t.Fatalf("ResolvePipeline failed: %v", err)
+	}
+
+	var stderr bytes.Buffer
+	results, err := runPipeline(context.Background(), stages, nil, runOptions{stdout: &bytes.Buffer{}, stderr: &stderr})
+	if err == nil {
+		t.Fatal("expected pipeline to fail")
+	}
+	if !strings.Contains(err.Error(), "col/fail failed") || exitCode(err) != 2 {
+		t.Errorf("expected the last failed stage to be reported, got %v", err)
+	}
+	for i, code := range []int{3, 2, 0} {
+		if results[i].ExitCode != code {
+			t.Errorf("expected stage %d to exit with %d, got %d", i, code, results[i].ExitCode)
+		}
+	}
+	if !strings.Contains(stderr.String(), "pipeline: col/gen=3 | col/fail=2 | col/cat=0") {
+		t.Errorf("expected status line, got %q", stderr.String())
+	}
+}
+
+func TestExecute_PipelineRunnable(t *testing.T) {
+	writeTestRunnables(t, map[string]string{
+		"gen":   "run: 'echo \"hello $1\"'\n",
+		"upper": "run: 'tr a-z A-Z'\n",
+		"all":   "pipeline: [gen, upper]\nafter: echo done\n",
+	})
+
+	ctx, err := ResolveCommand("col", []string{"all"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	result, err := (&Executor{Stdout: &stdout, SkipHistory: true}).Run(context.Background(), ctx, []string{"world"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if stdout.String() != "HELLO WORLD\ndone\n" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+	if run := result.Phase(PhaseRun); run == nil || run.Command != "gen | upper" {
+		t.Errorf("expected run phase of the pipeline, got %+v", result.Phases)
+	}
+}
+
+func TestResolvePipeline_Invalid(t *testing.T) {
+	writeTestRunnables(t, map[string]string{
+		"gen":  "run: echo\n",
+		"all":  "pipeline: [gen]\n",
+		"both": "run: echo\npipeline: [gen]\n",
+	})
+
+	if _, err := ResolvePipeline("", []string{"col/all"}); err == nil || !strings.Contains(err.Error(), "itself a pipeline") {
+		t.Errorf("expected nested pipeline to be rejected, got %v", err)
+	}
+	if _, err := ResolvePipeline("", []string{"gen"}); err == nil {
+		t.Error("expected stage without collection to be rejected")
+	}
+	if _, err := ResolvePipeline("col", []string{"missing"}); err == nil {
+		t.Error("expected missing runnable to be rejected")
+	}
+
+	ctx, err := ResolveCommand("col", []string{"both"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	_, err = (&Executor{SkipHistory: true}).Run(context.Background(), ctx, nil)
+	if err == nil || !strings.Contains(err.Error(), "cannot both be specified") {
+		t.Errorf("expected run and pipeline to be exclusive, got %v", err)
+	}
+}
+
+func TestExecute_SandboxedPipeline(t *testing.T) {
+	if err := checkSandboxSupported(); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+	escaped := filepath.Join(t.TempDir(), "escaped")
+	writeTestRunnables(t, map[string]string{
+		"write":     "run: 'echo no > " + escaped + "'\n",
+		"cat":       "run: cat\nconfirm: true\n",
+		"all":       "pipeline: [write, cat]\n",
+		"sandboxed": "pipeline: [write, cat]\nsandbox: true\n",
+	})
+
+	for _, name := range []string{"all", "sandboxed"} {
+		ctx, err := ResolveCommand("col", []string{name})
+		if err != nil {
+			t.Fatalf("ResolveCommand failed: %v", err)
+		}
+		// --sandbox and --yes apply to every stage
+		ctx.Sandbox = name == "all"
+		ctx.AssumeYes = true
+
+		var stdout, stderr bytes.Buffer
+		_, err = (&Executor{Stdout: &stdout, Stderr: &stderr, SkipHistory: true}).Run(context.Background(), ctx, nil)
+		if err == nil || !strings.Contains(err.Error(), "stage col/write failed") {
+			t.Errorf("%s: expected the write stage to fail, got %v: %s", name, err, stderr.String())
+		}
+		if _, err := os.Stat(escaped); !os.IsNotExist(err) {
+			t.Fatalf("%s: expected the stage to be sandboxed, got %v", name, err)
+		}
+	}
+}
+
+func TestRunPipeline_HooksStayOutOfTheStream(t *testing.T) {
+	writeTestRunnables(t, map[string]string{
+		"a": "run: echo data\nbefore: echo before-a\nafter: echo after-a\n",
+		"b": "run: cat -n\nbefore: echo before-b\n",
+	})
+
+	stages, err := ResolvePipeline("col", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("ResolvePipeline failed: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if _, err := runPipeline(context.Background(), stages, nil, runOptions{stdout: &stdout, stderr: &stderr}); err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+	if got := strings.Fields(stdout.String()); len(got) != 2 || got[0] != "1" || got[1] != "data" {
+		t.Errorf("expected only the run output in the stream, got %q", stdout.String())
+	}
+	for _, hook := range []string{"before-a", "after-a", "before-b"} {
+		if !strings.Contains(stderr.String(), hook+"\n") {
+			t.Errorf("expected %s in stderr, got %q", hook, stderr.String())
+		}
+	}
+}
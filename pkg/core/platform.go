@@ -0,0 +1,75 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"runtime"
+	"strings"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// currentPlatform is the os/arch pair shellican runs on.
+var currentPlatform = runtime.GOOS + "/" + runtime.GOARCH
+
+// matchPlatform reports whether pattern, an os or an os/arch pair, matches platform.
+func matchPlatform(pattern, platform string) bool {
+	if strings.Contains(pattern, "/") {
+		return pattern == platform
+	}
+	goos, _, _ := strings.Cut(platform, "/")
+	return pattern == goos
+}
+
+// supportsPlatform reports whether a runnable declaring platforms runs on platform.
+func supportsPlatform(cfg *config.RunnableConfig, platform string) bool {
+	if len(cfg.Platforms) == 0 {
+		return true
+	}
+	for _, pattern := range cfg.Platforms {
+		if matchPlatform(pattern, platform) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPlatform fails when the runnable does not support platform.
+func checkPlatform(cfg *config.RunnableConfig, name, platform string) error {
+	if supportsPlatform(cfg, platform) {
+		return nil
+	}
+	return fmt.Errorf("runnable '%s' is not supported on %s (platforms: %s)", name, platform, strings.Join(cfg.Platforms, ", "))
+}
+
+// applyOverrides returns cfg with the overrides matching platform applied, the
+// os override first and the os/arch one over it.
+func applyOverrides(cfg *config.RunnableConfig, platform string) *config.RunnableConfig {
+	if len(cfg.Overrides) == 0 {
+		return cfg
+	}
+	goos, _, _ := strings.Cut(platform, "/")
+
+	applied := *cfg
+	applied.Environments = maps.Clone(cfg.Environments)
+	for _, key := range []string{goos, platform} {
+		o, ok := cfg.Overrides[key]
+		if !ok {
+			continue
+		}
+		if o.Run != "" {
+			applied.Run = o.Run
+		}
+		if o.Before != "" {
+			applied.Before = o.Before
+		}
+		if o.After != "" {
+			applied.After = o.After
+		}
+		if len(o.Environments) > 0 && applied.Environments == nil {
+			applied.Environments = make(map[string]string)
+		}
+		maps.Copy(applied.Environments, o.Environments)
+	}
+	return &applied
+}
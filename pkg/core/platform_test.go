@@ -0,0 +1,79 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestSupportsPlatform(t *testing.T) {
+	tests := []struct {
+		platforms []string
+		platform  string
+		want      bool
+	}{
+		{nil, "linux/amd64", true},
+		{[]string{"linux"}, "linux/arm64", true},
+		{[]string{"darwin", "linux/amd64"}, "linux/amd64", true},
+		{[]string{"darwin", "linux/amd64"}, "linux/arm64", false},
+		{[]string{"darwin/arm64"}, "darwin/amd64", false},
+	}
+	for _, tt := range tests {
+		cfg := &config.RunnableConfig{Platforms: tt.platforms}
+		if got := supportsPlatform(cfg, tt.platform); got != tt.want {
+			t.Errorf("supportsPlatform(%v, %s) = %v, want %v", tt.platforms, tt.platform, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	cfg := &config.RunnableConfig{
+		Run:          "apt-get install jq",
+		Before:       "echo before",
+		Environments: map[string]string{"A": "1", "B": "1"},
+		Overrides: map[string]config.PlatformOverride{
+			"darwin":       {Run: "brew install jq", Environments: map[string]string{"A": "darwin"}},
+			"darwin/arm64": {Before: "echo arm", Environments: map[string]string{"A": "arm"}},
+			"linux":        {Run: "never"},
+		},
+	}
+
+	applied := applyOverrides(cfg, "darwin/arm64")
+	if applied.Run != "brew install jq" || applied.Before != "echo arm" {
+		t.Errorf("unexpected commands: run %q, before %q", applied.Run, applied.Before)
+	}
+	if applied.Environments["A"] != "arm" || applied.Environments["B"] != "1" {
+		t.Errorf("unexpected environments %v", applied.Environments)
+	}
+	if cfg.Run != "apt-get install jq" || cfg.Environments["A"] != "1" {
+		t.Error("expected the config not to be modified")
+	}
+
+	if applied := applyOverrides(cfg, "windows/amd64"); applied.Run != cfg.Run || applied.Before != cfg.Before {
+		t.Errorf("expected no override to apply, got %+v", applied)
+	}
+}
+
+func TestResolveCommand_Platforms(t *testing.T) {
+	goos, _, _ := strings.Cut(currentPlatform, "/")
+	writeTestRunnables(t, map[string]string{
+		"other": "run: 'true'\nplatforms: [plan9]\n",
+		"here":  "run: 'false'\nplatforms: [plan9, " + goos + "]\noverrides:\n  " + currentPlatform + ":\n    run: 'true'\n    environments:\n      TOOL: native\n",
+	})
+
+	if _, err := ResolveCommand("col", []string{"other"}); err == nil || !strings.Contains(err.Error(), "not supported on "+currentPlatform) {
+		t.Errorf("expected unsupported platform error, got %v", err)
+	}
+
+	ctx, err := ResolveCommand("col", []string{"here"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	if ctx.Config.Run != "true" || ctx.Environments["TOOL"] != "native" {
+		t.Errorf("expected overrides to apply, got run %q and env %v", ctx.Config.Run, ctx.Environments)
+	}
+	if ctx.EnvironmentSources["TOOL"] != EnvSourceRunnable {
+		t.Errorf("expected override environments to come from the runnable, got %q", ctx.EnvironmentSources["TOOL"])
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !unix
+
+package core
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// setProcessGroup leaves cmd as is: process groups are not supported on this
+// platform, so cancelling cmd only kills the process itself.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// setNice is not supported on this platform.
+func setNice(pid, nice int) error {
+	return fmt.Errorf("nice is not supported on this platform")
+}
+
+// errNoSessions reports that background runs cannot be started on this platform.
+var errNoSessions = fmt.Errorf("background runs are not supported on this platform")
+
+func startSession(cmd *exec.Cmd) error {
+	return errNoSessions
+}
+
+func leadsGroup(pid, pgid int) bool {
+	return false
+}
+
+func terminateProcess(pid int) error {
+	return errNoSessions
+}
+
+func killProcess(pid int) error {
+	return errNoSessions
+}
@@ -0,0 +1,64 @@
+//go:build unix
+
+package core
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts cmd in its own process group, which is terminated as
+// a whole when cmd is cancelled.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd.Process.Pid)
+	}
+}
+
+// killProcessGroup asks the process group led by pid to terminate and kills it
+// if it is still around after killGracePeriod.
+func killProcessGroup(pid int) error {
+	if err := syscall.Kill(-pid, syscall.SIGTERM); err != nil {
+		return err
+	}
+	time.AfterFunc(killGracePeriod, func() {
+		_ = syscall.Kill(-pid, syscall.SIGKILL)
+	})
+	return nil
+}
+
+// setNice sets the nice value of the process pid.
+func setNice(pid, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
+
+// startSession makes cmd start in a session of its own, away from the terminal.
+func startSession(cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	return nil
+}
+
+// leadsGroup reports whether the process pid is alive and leads the process group pgid.
+func leadsGroup(pid, pgid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil && !errors.Is(err, syscall.EPERM) {
+		return false
+	}
+	got, err := syscall.Getpgid(pid)
+	return err == nil && got == pgid
+}
+
+// terminateProcess asks the process pid, or the process group -pid, to terminate.
+func terminateProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// killProcess kills the process pid, or the process group -pid, unless it is already gone.
+func killProcess(pid int) error {
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// promptedEnv returns the required variables of a collection and its runnable
+// that are asked for when missing.
+func promptedEnv(colReq, runReq config.RequiresConfig) []config.EnvRequirement {
+	var prompted []config.EnvRequirement
+	for _, env := range append(slices.Clone(colReq.Env), runReq.Env...) {
+		if env.Prompt.Enabled {
+			prompted = append(prompted, env)
+		}
+	}
+	return prompted
+}
+
+// promptMissingEnv asks for the prompted variables ctx does not set and
+// returns a context setting them. It fails when stdin is not a terminal.
+func promptMissingEnv(ctx *ExecutionContext, opts runOptions) (*ExecutionContext, error) {
+	envs := envMap(ctx)
+	var missing []config.EnvRequirement
+	var names []string
+	for _, env := range ctx.Prompts {
+		if _, ok := envs[env.Name]; !ok && !slices.Contains(names, env.Name) {
+			missing = append(missing, env)
+			names = append(names, env.Name)
+		}
+	}
+	if len(missing) == 0 {
+		return ctx, nil
+	}
+
+	if !isTerminal(opts.stdin) {
+		return nil, fmt.Errorf("environment variables not set: %s; stdin is not a terminal to prompt for them, set them with -e NAME=VALUE", strings.Join(names, ", "))
+	}
+
+	prompted := *ctx
+	prompted.Environments = maps.Clone(ctx.Environments)
+	prompted.EnvironmentSources = maps.Clone(ctx.EnvironmentSources)
+	if prompted.Environments == nil {
+		prompted.Environments = make(map[string]string)
+	}
+	if prompted.EnvironmentSources == nil {
+		prompted.EnvironmentSources = make(map[string]string)
+	}
+
+	r := bufio.NewReader(opts.stdin)
+	for _, env := range missing {
+		var hide func() (func(), error)
+		if f, ok := opts.stdin.(*os.File); ok {
+			hide = func() (func(), error) { return disableEcho(f) }
+		}
+		value, err := readInput(r, opts.stderr, env, hide)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", env.Name, err)
+		}
+		prompted.Environments[env.Name] = value
+		prompted.EnvironmentSources[env.Name] = EnvSourcePrompt
+	}
+	return &prompted, nil
+}
+
+// readInput writes the prompt of env to w and reads its value from r until it
+// is valid. hide, when not nil, stops the input of secrets from being echoed.
+func readInput(r *bufio.Reader, w io.Writer, env config.EnvRequirement, hide func() (func(), error)) (string, error) {
+	p := env.Prompt
+	if p.Default != "" && len(p.Choices) > 0 && !slices.Contains(p.Choices, p.Default) {
+		return "", fmt.Errorf("default '%s' is not one of the choices", p.Default)
+	}
+
+	message := p.Message
+	if message == "" {
+		message = env.Name
+	}
+	if len(p.Choices) > 0 {
+		message += " [" + strings.Join(p.Choices, "/") + "]"
+	}
+	if p.Default != "" && !p.Secret {
+		message += " (default: " + p.Default + ")"
+	}
+
+	for {
+		_, _ = fmt.Fprintf(w, "%s: ", message)
+		answer, err := readLine(r, w, p.Secret, hide)
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = p.Default
+		}
+
+		switch {
+		case answer == "":
+			_, _ = fmt.Fprintln(w, "A value is required.")
+		case len(p.Choices) > 0 && !slices.Contains(p.Choices, answer):
+			_, _ = fmt.Fprintf(w, "Choose one of: %s\n", strings.Join(p.Choices, ", "))
+		default:
+			return answer, nil
+		}
+	}
+}
+
+// readLine reads a line from r without its line ending, without echoing it if secret.
+func readLine(r *bufio.Reader, w io.Writer, secret bool, hide func() (func(), error)) (string, error) {
+	if secret && hide != nil {
+		restore, err := hide()
+		if err != nil {
+			return "", fmt.Errorf("failed to hide input: %w", err)
+		}
+		defer func() {
+			restore()
+			// the line ending was not echoed either
+			_, _ = fmt.Fprintln(w)
+		}()
+	}
+
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
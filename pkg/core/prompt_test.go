@@ -0,0 +1,116 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestReadInput(t *testing.T) {
+	choices := config.PromptConfig{Enabled: true, Message: "Target", Choices: []string{"staging", "production"}, Default: "staging"}
+	tests := []struct {
+		name   string
+		prompt config.PromptConfig
+		input  string
+		want   string
+		output string
+	}{
+		{"plain", config.PromptConfig{Enabled: true}, "value\n", "value", "TOKEN: "},
+		{"default", choices, "\n", "staging", "Target [staging/production] (default: staging): "},
+		{"invalid choice", choices, "prod\nproduction\n", "production", "Choose one of: staging, production\n"},
+		{"required", config.PromptConfig{Enabled: true}, "\nlast", "last", "A value is required.\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		env := config.EnvRequirement{Name: "TOKEN", Prompt: tt.prompt}
+		got, err := readInput(bufio.NewReader(strings.NewReader(tt.input)), &out, env, nil)
+		if err != nil {
+			t.Fatalf("%s: readInput failed: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+		if !strings.Contains(out.String(), tt.output) {
+			t.Errorf("%s: expected output to contain %q, got %q", tt.name, tt.output, out.String())
+		}
+	}
+
+	env := config.EnvRequirement{Name: "TOKEN", Prompt: config.PromptConfig{Enabled: true}}
+	if _, err := readInput(bufio.NewReader(strings.NewReader("")), &bytes.Buffer{}, env, nil); err == nil {
+		t.Error("expected error at end of input")
+	}
+	env.Prompt = config.PromptConfig{Enabled: true, Choices: []string{"a"}, Default: "b"}
+	if _, err := readInput(bufio.NewReader(strings.NewReader("a\n")), &bytes.Buffer{}, env, nil); err == nil {
+		t.Error("expected error for a default that is not a choice")
+	}
+}
+
+func TestReadInput_Secret(t *testing.T) {
+	hidden, restored := false, false
+	hide := func() (func(), error) {
+		hidden = true
+		return func() { restored = true }, nil
+	}
+
+	var out bytes.Buffer
+	env := config.EnvRequirement{Name: "TOKEN", Prompt: config.PromptConfig{Enabled: true, Secret: true, Default: "s3cr3t"}}
+	got, err := readInput(bufio.NewReader(strings.NewReader("hunter2\n")), &out, env, hide)
+	if err != nil {
+		t.Fatalf("readInput failed: %v", err)
+	}
+	if got != "hunter2" || !hidden || !restored {
+		t.Errorf("expected hidden input to be read and echo restored, got %q", got)
+	}
+	if out.String() != "TOKEN: \n" {
+		t.Errorf("expected the default of a secret not to be shown, got %q", out.String())
+	}
+}
+
+func TestPromptMissingEnv_NonInteractive(t *testing.T) {
+	t.Setenv("SHELLICAN_PROMPT_SET", "1")
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config:       &config.RunnableConfig{Run: "true"},
+		Prompts: []config.EnvRequirement{
+			{Name: "SHELLICAN_PROMPT_SET", Prompt: config.PromptConfig{Enabled: true}},
+			{Name: "SHELLICAN_PROMPT_UNSET", Prompt: config.PromptConfig{Enabled: true}},
+		},
+	}
+
+	_, err := (&Executor{Stdin: strings.NewReader("value\n"), SkipHistory: true}).Run(context.Background(), ctx, nil)
+	if err == nil {
+		t.Fatal("expected missing variable to fail without a terminal")
+	}
+	if !strings.Contains(err.Error(), "SHELLICAN_PROMPT_UNSET") || strings.Contains(err.Error(), "SHELLICAN_PROMPT_SET,") {
+		t.Errorf("expected only the missing variable to be reported, got %v", err)
+	}
+
+	ctx.Environments = map[string]string{"SHELLICAN_PROMPT_UNSET": "given"}
+	prompted, err := promptMissingEnv(ctx, runOptions{})
+	if err != nil || prompted != ctx {
+		t.Errorf("expected nothing to be asked when every variable is set, got %v", err)
+	}
+}
+
+func TestResolveCommand_PromptedEnv(t *testing.T) {
+	writeTestRunnables(t, map[string]string{
+		"ask":   "run: 'true'\nrequires:\n  env:\n    - name: SHELLICAN_PROMPT_UNSET\n      prompt: true\n",
+		"plain": "run: 'true'\nrequires:\n  env: [SHELLICAN_PROMPT_UNSET]\n",
+	})
+
+	ctx, err := ResolveCommand("col", []string{"ask"})
+	if err != nil {
+		t.Fatalf("expected a prompted variable not to fail resolution, got %v", err)
+	}
+	if len(ctx.Prompts) != 1 || ctx.Prompts[0].Name != "SHELLICAN_PROMPT_UNSET" {
+		t.Errorf("unexpected prompts %+v", ctx.Prompts)
+	}
+	if _, err := ResolveCommand("col", []string{"plain"}); err == nil {
+		t.Error("expected a variable without prompt to fail resolution")
+	}
+}
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+const (
+	// defaultVersionRegex extracts a dotted version number from a version command output.
+	defaultVersionRegex = `\d+(?:\.\d+)+`
+	// versionCommandTimeout bounds how long a version command may run.
+	versionCommandTimeout = 10 * time.Second
+)
+
+// CheckRequirements verifies the requirements of the runnable of ctx and its
+// collection against the environment its commands would start with, running
+// the version commands of the required binaries there, under the sandbox and
+// limits of the runnable. The variables of its matrix, and of the matrix
+// overrides, count as set since every combination sets them.
+// Every missing requirement is reported at once instead of failing on the first one.
+func CheckRequirements(ctx *ExecutionContext, matrix map[string][]string) error {
+	envs := requirementEnv(ctx, matrix)
+	missing := append(missingRequirements(ctx.CollectionRequires, ctx.CollectionPath, envs),
+		missingRequirements(ctx.Config.Requires, ctx.RunnablePath, envs)...)
+
+	limits, err := resolveLimits(ctx.Config.Limits)
+	if err != nil {
+		return fmt.Errorf("invalid limits: %w", err)
+	}
+	sandbox, err := resolveSandbox(ctx)
+	if err != nil {
+		return err
+	}
+	missing = append(missing, checkVersions(context.Background(), ctx, runOptions{limits: limits, sandbox: sandbox})...)
+	return requirementsError(missing)
+}
+
+// requirementEnv returns the environment requirements of ctx are checked against.
+func requirementEnv(ctx *ExecutionContext, matrix map[string][]string) map[string]string {
+	envs := envMap(ctx)
+	for _, keys := range []map[string][]string{ctx.Config.Matrix, matrix} {
+		for name := range keys {
+			if _, ok := envs[name]; !ok {
+				envs[name] = ""
+			}
+		}
+	}
+	return envs
+}
+
+// checkRequirements verifies the binaries, variables and files required by a
+// runnable and its collection, without running version commands.
+// Every missing requirement is reported at once instead of failing on the first one.
+func checkRequirements(colReq config.RequiresConfig, colPath string, runReq config.RequiresConfig, runPath string, envs map[string]string) error {
+	var missing []string
+	missing = append(missing, missingRequirements(colReq, colPath, envs)...)
+	missing = append(missing, missingRequirements(runReq, runPath, envs)...)
+	return requirementsError(missing)
+}
+
+func requirementsError(missing []string) error {
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("missing requirements:\n  - %s", strings.Join(missing, "\n  - "))
+}
+
+// missingRequirements lists what is not satisfied in req by envs, the
+// environment commands start with. Binaries are searched in its PATH, files
+// and binaries given as a path are relative to baseDir.
+func missingRequirements(req config.RequiresConfig, baseDir string, envs map[string]string) []string {
+	var missing []string
+
+	for _, bin := range req.Binaries {
+		if bin.Name == "" {
+			missing = append(missing, "binary requirement without a name")
+		} else if !lookPath(bin.Name, envs["PATH"], baseDir) {
+			missing = append(missing, fmt.Sprintf("binary '%s' not found in PATH", bin.Name))
+		}
+	}
+
+	for _, env := range req.Env {
+		// variables with a prompt are asked for when the runnable starts
+		if _, ok := envs[env.Name]; !ok && !env.Prompt.Enabled {
+			missing = append(missing, fmt.Sprintf("environment variable '%s' is not set", env.Name))
+		}
+	}
+
+	for _, file := range req.Files {
+		path := os.ExpandEnv(file)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			missing = append(missing, fmt.Sprintf("file '%s' not found", file))
+		}
+	}
+
+	return missing
+}
+
+// lookPath reports whether name is an executable file in one of the
+// directories of path, as exec.LookPath does with the PATH of the process.
+// Names with a slash, and relative directories, are relative to dir.
+func lookPath(name, path, dir string) bool {
+	if strings.Contains(name, "/") {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return isExecutableFile(name)
+	}
+	for _, d := range filepath.SplitList(path) {
+		if !filepath.IsAbs(d) {
+			d = filepath.Join(dir, d)
+		}
+		if isExecutableFile(filepath.Join(d, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && isExecutable(info)
+}
+
+// versionRequirements returns the binaries of the collection and the runnable
+// of ctx that need a minimum version.
+func versionRequirements(ctx *ExecutionContext) []config.BinaryRequirement {
+	var bins []config.BinaryRequirement
+	for _, bin := range append(slices.Clone(ctx.CollectionRequires.Binaries), ctx.Config.Requires.Binaries...) {
+		if bin.Name != "" && bin.MinVersion != "" {
+			bins = append(bins, bin)
+		}
+	}
+	return bins
+}
+
+// versionCommand returns the command printing the version of bin.
+func versionCommand(bin config.BinaryRequirement) string {
+	if bin.VersionCommand != "" {
+		return bin.VersionCommand
+	}
+	return bin.Name + " --version"
+}
+
+// checkVersions runs the version commands of the required binaries of ctx
+// found in its environment, with opts, and lists the binaries that are too old
+// or whose version cannot be told.
+func checkVersions(c context.Context, ctx *ExecutionContext, opts runOptions) []string {
+	env := buildEnv(ctx)
+	path := envMap(ctx)["PATH"]
+	var missing []string
+	for _, bin := range versionRequirements(ctx) {
+		// binaries that are not found are already reported
+		if !lookPath(bin.Name, path, ctx.RunnablePath) && !lookPath(bin.Name, path, ctx.CollectionPath) {
+			continue
+		}
+		version, err := binaryVersion(c, bin, env, ctx.RunnablePath, opts)
+		if err != nil {
+			missing = append(missing, fmt.Sprintf("binary '%s': %v", bin.Name, err))
+		} else if compareVersions(version, bin.MinVersion) < 0 {
+			missing = append(missing, fmt.Sprintf("binary '%s' version %s is older than required %s", bin.Name, version, bin.MinVersion))
+		}
+	}
+	return missing
+}
+
+// binaryVersion runs the version command of bin and extracts the version from its output.
+func binaryVersion(c context.Context, bin config.BinaryRequirement, env []string, dir string, opts runOptions) (string, error) {
+	command := versionCommand(bin)
+	pattern := bin.VersionRegex
+	if pattern == "" {
+		pattern = defaultVersionRegex
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid version regex: %w", err)
+	}
+
+	c, cancel := context.WithTimeout(c, versionCommandTimeout)
+	defer cancel()
+	var output bytes.Buffer
+	probe := opts.quiet()
+	probe.stdout = &output
+	probe.stderr = &output
+	if err := runShell(c, command, nil, env, dir, probe); err != nil {
+		return "", fmt.Errorf("version command failed: %w", err)
+	}
+
+	match := re.FindStringSubmatch(output.String())
+	if match == nil {
+		return "", fmt.Errorf("no version found in output of '%s'", command)
+	}
+	if len(match) > 1 && match[1] != "" {
+		return match[1], nil
+	}
+	return match[0], nil
+}
+
+// compareVersions compares dotted versions numerically, returning -1, 0 or 1.
+// Missing segments count as zero and non-numeric suffixes are ignored.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := versionSegment(as, i), versionSegment(bs, i)
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionSegment(segments []string, i int) int {
+	if i >= len(segments) {
+		return 0
+	}
+	segment := segments[i]
+	end := 0
+	for end < len(segment) && segment[end] >= '0' && segment[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(segment[:end])
+	return n
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestCheckRequirements(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "present.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	t.Setenv("SHELLICAN_REQ_SET", "1")
+
+	satisfied := config.RequiresConfig{
+		Binaries: []config.BinaryRequirement{
+			{Name: "sh"},
+			{Name: "sh", MinVersion: "1.2", VersionCommand: "echo 'tool version 1.10.0'"},
+		},
+		Env:   []config.EnvRequirement{{Name: "SHELLICAN_REQ_SET"}, {Name: "DECLARED"}},
+		Files: []string{"present.txt"},
+	}
+	envs := envMap(&ExecutionContext{Environments: map[string]string{"DECLARED": "v"}})
+	if err := checkRequirements(config.RequiresConfig{}, tempDir, satisfied, tempDir, envs); err != nil {
+		t.Errorf("Expected requirements to be satisfied, got %v", err)
+	}
+
+	unsatisfied := config.RequiresConfig{
+		Binaries: []config.BinaryRequirement{
+			{Name: "shellican-missing-binary"},
+			{Name: "sh", MinVersion: "2.0", VersionCommand: "echo 'v1.9'", VersionRegex: `v(\d+\.\d+)`},
+		},
+		Env:   []config.EnvRequirement{{Name: "SHELLICAN_REQ_UNSET"}},
+		Files: []string{"absent.txt"},
+	}
+	err := checkRequirements(unsatisfied, tempDir, config.RequiresConfig{}, tempDir, envs)
+	if err == nil {
+		t.Fatal("Expected missing requirements error")
+	}
+	for _, want := range []string{"shellican-missing-binary", "SHELLICAN_REQ_UNSET", "absent.txt"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected report to mention %q, got %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "older than required") {
+		t.Errorf("Expected versions not to be checked, got %v", err)
+	}
+}
+
+func TestCheckVersions(t *testing.T) {
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		CollectionRequires: config.RequiresConfig{Binaries: []config.BinaryRequirement{
+			{Name: "sh", MinVersion: "1.2", VersionCommand: "echo 'tool version 1.10.0'"},
+		}},
+		Config: &config.RunnableConfig{Requires: config.RequiresConfig{Binaries: []config.BinaryRequirement{
+			{Name: "sh"},
+			{Name: "sh", MinVersion: "2.0", VersionCommand: "echo \"v1.9 in $PWD\"", VersionRegex: `v(\d+\.\d+)`},
+			{Name: "sh", MinVersion: "1.0", VersionCommand: "exit 1"},
+		}}},
+	}
+
+	missing := checkVersions(context.Background(), ctx, runOptions{})
+	if len(missing) != 2 {
+		t.Fatalf("Expected two missing requirements, got %q", missing)
+	}
+	for i, want := range []string{"1.9 is older than required 2.0", "version command failed"} {
+		if !strings.Contains(missing[i], want) {
+			t.Errorf("Expected report to mention %q, got %q", want, missing[i])
+		}
+	}
+}
+
+func TestResolveCommand_ChecksVersions(t *testing.T) {
+	colDir := writeTestRunnables(t, map[string]string{
+		"build": "run: 'true'\nrequires:\n  binaries:\n    - name: sh\n      min_version: '2.0'\n      version_command: 'touch side-effect; echo 1.0'\n  env: [SHELLICAN_REQ_UNSET]\n",
+	})
+	sideEffect := filepath.Join(colDir, "build", "side-effect")
+
+	// a plan lists the version checks without running them
+	ctx, err := ResolveCommandUnchecked("col", []string{"build"}, nil)
+	if err != nil {
+		t.Fatalf("ResolveCommandUnchecked failed: %v", err)
+	}
+	plan, err := ExplainContext(ctx, nil)
+	if err != nil {
+		t.Fatalf("ExplainContext failed: %v", err)
+	}
+	if len(plan.MissingRequirements) != 1 || !strings.Contains(plan.MissingRequirements[0], "SHELLICAN_REQ_UNSET") {
+		t.Errorf("Expected the plan to report the missing variable, got %q", plan.MissingRequirements)
+	}
+	if len(plan.VersionChecks) != 1 || plan.VersionChecks[0] != "sh at least 2.0: touch side-effect; echo 1.0" {
+		t.Errorf("Expected the plan to list the version check, got %q", plan.VersionChecks)
+	}
+	if _, err := os.Stat(sideEffect); !os.IsNotExist(err) {
+		t.Fatalf("Expected no version command to run for a plan, got %v", err)
+	}
+
+	// resolving reports every missing requirement at once
+	_, err = ResolveCommand("col", []string{"build"})
+	if err == nil || !strings.Contains(err.Error(), "SHELLICAN_REQ_UNSET") || !strings.Contains(err.Error(), "1.0 is older than required 2.0") {
+		t.Errorf("Expected missing variable and version errors, got %v", err)
+	}
+	if _, err := os.Stat(sideEffect); err != nil {
+		t.Errorf("Expected the version command to run in the runnable directory: %v", err)
+	}
+}
+
+func TestCheckRequirements_RunPath(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "shellican-req-tool"), []byte("#!/bin/sh\necho 'tool 1.5.0'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestRunnables(t, map[string]string{
+		"build": "run: 'true'\nenvironments:\n  PATH: " + bin + ":/usr/bin:/bin\nrequires:\n  binaries:\n    - name: shellican-req-tool\n      min_version: '1.2'\n",
+	})
+
+	// the binary is only in the PATH the runnable starts with
+	if _, err := ResolveCommand("col", []string{"build"}); err != nil {
+		t.Errorf("Expected the binary to be found in the PATH of the runnable, got %v", err)
+	}
+	ctx, err := ResolveCommandUnchecked("col", []string{"build"}, map[string]string{"PATH": "/usr/bin:/bin"})
+	if err != nil {
+		t.Fatalf("ResolveCommandUnchecked failed: %v", err)
+	}
+	err = CheckRequirements(ctx, nil)
+	if err == nil || !strings.Contains(err.Error(), "binary 'shellican-req-tool' not found in PATH") || strings.Count(err.Error(), "\n  - ") != 1 {
+		t.Errorf("Expected only the binary to be reported missing, got %v", err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.10", "1.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"v2.0", "1.99", 1},
+		{"1.2.0-rc1", "1.2", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
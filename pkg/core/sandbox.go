@@ -0,0 +1,37 @@
+package core
+
+import (
+	"path/filepath"
+)
+
+// sandboxPolicy restricts what the commands of a run may do.
+type sandboxPolicy struct {
+	// writable lists the absolute paths below which writes are allowed.
+	writable []string
+	network  bool
+}
+
+// sandboxDevices are always writable inside the sandbox, when present.
+var sandboxDevices = []string{"/dev/null", "/dev/zero", "/dev/full", "/dev/tty", "/dev/pts"}
+
+// resolveSandbox returns the sandbox policy of ctx, or nil when the runnable
+// is not sandboxed. Writable paths are relative to the runnable directory.
+func resolveSandbox(ctx *ExecutionContext) (*sandboxPolicy, error) {
+	cfg := ctx.Config.Sandbox
+	if !cfg.Enabled && !ctx.Sandbox {
+		return nil, nil
+	}
+
+	p := &sandboxPolicy{network: cfg.Network == nil || *cfg.Network}
+	for _, path := range cfg.Writable {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(ctx.RunnablePath, path)
+		}
+		p.writable = append(p.writable, filepath.Clean(path))
+	}
+
+	if err := checkSandboxSupported(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Landlock system calls share their numbers across architectures.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1
+	landlockRulePathBeneath      = 1
+
+	oPath           = 0x200000
+	prSetNoNewPrivs = 38
+)
+
+// Landlock filesystem rights restricting writes. Reads are never restricted.
+const (
+	landlockWriteFile  = 1 << 1
+	landlockRemoveDir  = 1 << 4
+	landlockRemoveFile = 1 << 5
+	landlockMakeChar   = 1 << 6
+	landlockMakeDir    = 1 << 7
+	landlockMakeReg    = 1 << 8
+	landlockMakeSock   = 1 << 9
+	landlockMakeFifo   = 1 << 10
+	landlockMakeBlock  = 1 << 11
+	landlockMakeSym    = 1 << 12
+	landlockRefer      = 1 << 13 // ABI 2
+	landlockTruncate   = 1 << 14 // ABI 3
+
+	// landlockFileRights are the rights that apply to a file rather than a directory.
+	landlockFileRights = landlockWriteFile | landlockTruncate
+)
+
+// Landlock network rights, available from ABI 4.
+const (
+	landlockBindTCP    = 1 << 0
+	landlockConnectTCP = 1 << 1
+)
+
+// landlockABI returns the Landlock ABI version of the running kernel.
+func landlockABI() (int, error) {
+	v, _, errno := syscall.RawSyscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(v), nil
+}
+
+// checkSandboxSupported reports whether commands can be sandboxed on this kernel.
+func checkSandboxSupported() error {
+	if _, err := landlockABI(); err != nil {
+		return fmt.Errorf("sandboxing needs Landlock, which is not available: %w", err)
+	}
+	return nil
+}
+
+// startSandboxed starts cmd so that it, and everything it spawns, can only
+// write below the writable paths of p and, unless allowed, can neither open
+// nor accept TCP connections. Landlock does not restrict other sockets, so UDP
+// and raw sockets stay usable.
+func startSandboxed(cmd *exec.Cmd, p *sandboxPolicy) error {
+	abi, err := landlockABI()
+	if err != nil {
+		return fmt.Errorf("sandboxing needs Landlock, which is not available: %w", err)
+	}
+	handled := uint64(landlockWriteFile | landlockRemoveDir | landlockRemoveFile | landlockMakeChar |
+		landlockMakeDir | landlockMakeReg | landlockMakeSock | landlockMakeFifo | landlockMakeBlock | landlockMakeSym)
+	if abi >= 2 {
+		handled |= landlockRefer
+	}
+	if abi >= 3 {
+		handled |= landlockTruncate
+	}
+
+	var handledNet uint64
+	if !p.network {
+		if abi < 4 {
+			return fmt.Errorf("disabling the network needs Landlock ABI 4 (Linux 6.7), the kernel provides %d", abi)
+		}
+		handledNet = landlockBindTCP | landlockConnectTCP
+	}
+
+	rulesetFd, err := createRuleset(handled, handledNet)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = syscall.Close(rulesetFd) }()
+
+	for _, path := range sandboxDevices {
+		if err := addPathRule(rulesetFd, path, handled); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	for _, path := range p.writable {
+		if err := addPathRule(rulesetFd, path, handled); err != nil {
+			return err
+		}
+	}
+
+	// Landlock restricts the calling thread and the processes it forks. The
+	// restricted thread is never unlocked, so the runtime discards it once
+	// this goroutine returns instead of running other goroutines on it.
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		if err := restrictThread(rulesetFd); err != nil {
+			errCh <- err
+			return
+		}
+		errCh <- cmd.Start()
+	}()
+	return <-errCh
+}
+
+// createRuleset creates a ruleset denying the given rights unless a rule allows them.
+func createRuleset(handledFs, handledNet uint64) (int, error) {
+	attr := struct{ handledAccessFs, handledAccessNet uint64 }{handledFs, handledNet}
+	size := unsafe.Sizeof(attr)
+	if handledNet == 0 {
+		// older kernels reject the larger struct
+		size = unsafe.Sizeof(attr.handledAccessFs)
+	}
+	fd, _, errno := syscall.RawSyscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), size, 0)
+	if errno != 0 {
+		return -1, fmt.Errorf("failed to create sandbox ruleset: %w", errno)
+	}
+	return int(fd), nil
+}
+
+// addPathRule allows the write rights of handled below path.
+func addPathRule(rulesetFd int, path string, handled uint64) error {
+	fd, err := syscall.Open(path, oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open writable path %s: %w", path, err)
+	}
+	defer func() { _ = syscall.Close(fd) }()
+
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return fmt.Errorf("failed to stat writable path %s: %w", path, err)
+	}
+	allowed := handled
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		allowed &= landlockFileRights
+	}
+
+	// struct landlock_path_beneath_attr is packed: a u64 followed by an s32
+	var attr [12]byte
+	*(*uint64)(unsafe.Pointer(&attr[0])) = allowed
+	*(*int32)(unsafe.Pointer(&attr[8])) = int32(fd)
+	if _, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(rulesetFd), landlockRulePathBeneath,
+		uintptr(unsafe.Pointer(&attr[0])), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to allow writes to %s: %w", path, errno)
+	}
+	return nil
+}
+
+// restrictThread enforces the ruleset on the calling thread.
+func restrictThread(rulesetFd int) error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("failed to set no_new_privs: %w", errno)
+	}
+	if _, _, errno := syscall.RawSyscall(sysLandlockRestrictSelf, uintptr(rulesetFd), 0, 0); errno != 0 {
+		return fmt.Errorf("failed to enforce sandbox: %w", errno)
+	}
+	return nil
+}
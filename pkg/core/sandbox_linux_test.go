@@ -0,0 +1,50 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestExecute_SandboxWithoutNetwork(t *testing.T) {
+	if err := checkSandboxSupported(); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+	if abi, _ := landlockABI(); abi < 4 {
+		t.Skipf("Landlock ABI %d cannot restrict the network", abi)
+	}
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is needed to open a TCP connection")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	network := false
+	var out bytes.Buffer
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Run:     bash + " -c 'echo > /dev/tcp/127.0.0.1/" + port + "'",
+			Sandbox: config.SandboxConfig{Enabled: true, Network: &network},
+		},
+	}
+	if err := execute(context.Background(), ctx, nil, runOptions{stdout: &out, stderr: &out}); err == nil {
+		t.Fatal("expected the connection to be denied")
+	}
+
+	network = true
+	if err := execute(context.Background(), ctx, nil, runOptions{stdout: &out, stderr: &out}); err != nil {
+		t.Errorf("expected the connection to succeed with network allowed: %v: %s", err, out.String())
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package core
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// checkSandboxSupported reports whether commands can be sandboxed on this platform.
+func checkSandboxSupported() error {
+	return fmt.Errorf("sandboxing is only supported on Linux")
+}
+
+// startSandboxed starts cmd restricted by p.
+func startSandboxed(cmd *exec.Cmd, p *sandboxPolicy) error {
+	return checkSandboxSupported()
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestResolveSandbox(t *testing.T) {
+	ctx := &ExecutionContext{RunnablePath: "/runnables/a", Config: &config.RunnableConfig{}}
+	p, err := resolveSandbox(ctx)
+	if err != nil || p != nil {
+		t.Fatalf("expected no sandbox, got %v, %v", p, err)
+	}
+
+	if err := checkSandboxSupported(); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+
+	ctx.Config.Sandbox = config.SandboxConfig{Enabled: true, Writable: []string{"out", "/tmp/x/"}}
+	p, err = resolveSandbox(ctx)
+	if err != nil {
+		t.Fatalf("resolveSandbox failed: %v", err)
+	}
+	want := []string{"/runnables/a/out", "/tmp/x"}
+	if len(p.writable) != 2 || p.writable[0] != want[0] || p.writable[1] != want[1] {
+		t.Errorf("expected writable %v, got %v", want, p.writable)
+	}
+	if !p.network {
+		t.Error("expected network to be allowed by default")
+	}
+
+	// --sandbox forces it for runnables that do not declare one
+	ctx.Config.Sandbox = config.SandboxConfig{}
+	ctx.Sandbox = true
+	if p, err = resolveSandbox(ctx); err != nil || p == nil {
+		t.Errorf("expected forced sandbox, got %v, %v", p, err)
+	}
+}
+
+func TestExecute_SandboxRestrictsWrites(t *testing.T) {
+	if err := checkSandboxSupported(); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "out"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	ctx := &ExecutionContext{
+		RunnablePath: dir,
+		Config: &config.RunnableConfig{
+			Run:     "echo ok > out/allowed && echo ok > /dev/null && echo no > denied",
+			Sandbox: config.SandboxConfig{Enabled: true, Writable: []string{"out"}},
+		},
+	}
+
+	if err := execute(context.Background(), ctx, nil, runOptions{stdout: &out, stderr: &out}); err == nil {
+		t.Fatal("expected the write outside the writable paths to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "allowed")); err != nil {
+		t.Errorf("expected write to a writable path to succeed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "denied")); !os.IsNotExist(err) {
+		t.Errorf("expected write outside the writable paths to be denied, got %v", err)
+	}
+
+	// the test process itself is not restricted
+	if err := os.WriteFile(filepath.Join(dir, "after"), nil, 0644); err != nil {
+		t.Errorf("expected the caller to stay unrestricted: %v", err)
+	}
+}
+
+func TestExecute_SandboxRestrictsWaitForProbe(t *testing.T) {
+	if err := checkSandboxSupported(); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+
+	escaped := filepath.Join(t.TempDir(), "escaped")
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Run:     "true",
+			Sandbox: config.SandboxConfig{Enabled: true},
+			WaitFor: []config.WaitCondition{{Command: "touch " + escaped, Timeout: 200 * time.Millisecond, Interval: 50 * time.Millisecond}},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := execute(context.Background(), ctx, nil, runOptions{stdout: &out, stderr: &out}); err == nil {
+		t.Fatal("expected the probe to fail")
+	}
+	if _, err := os.Stat(escaped); !os.IsNotExist(err) {
+		t.Errorf("expected the probe to be sandboxed, got %v", err)
+	}
+}
+
+func TestExecute_SandboxRestrictsReadyCheck(t *testing.T) {
+	if err := checkSandboxSupported(); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+
+	escaped := filepath.Join(t.TempDir(), "escaped")
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Run:     "sleep 5",
+			Sandbox: config.SandboxConfig{Enabled: true},
+			Service: config.ServiceConfig{Ready: "touch " + escaped, ReadyTimeout: 300 * time.Millisecond},
+		},
+	}
+
+	out := &syncBuffer{}
+	if err := execute(context.Background(), ctx, nil, runOptions{stdout: out, stderr: out}); err == nil {
+		t.Fatal("expected the service not to become ready")
+	}
+	if _, err := os.Stat(escaped); !os.IsNotExist(err) {
+		t.Errorf("expected the ready check to be sandboxed, got %v", err)
+	}
+}
+
+func TestCheckRequirements_SandboxRestrictsVersionCommand(t *testing.T) {
+	if err := checkSandboxSupported(); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+
+	escaped := filepath.Join(t.TempDir(), "escaped")
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Run:     "true",
+			Sandbox: config.SandboxConfig{Enabled: true},
+			Requires: config.RequiresConfig{Binaries: []config.BinaryRequirement{
+				{Name: "sh", MinVersion: "1.0", VersionCommand: "touch " + escaped + "; echo 1.0"},
+			}},
+		},
+	}
+
+	if err := CheckRequirements(ctx, nil); err != nil {
+		t.Fatalf("CheckRequirements failed: %v", err)
+	}
+	if _, err := os.Stat(escaped); !os.IsNotExist(err) {
+		t.Errorf("expected the version command to be sandboxed, got %v", err)
+	}
+}
+
+func TestExecute_SandboxRestrictsNotifyCommand(t *testing.T) {
+	if err := checkSandboxSupported(); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+
+	escaped := filepath.Join(t.TempDir(), "escaped")
+	ctx := &ExecutionContext{
+		Collection:   "col",
+		Runnable:     "build",
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Run:     "true",
+			Sandbox: config.SandboxConfig{Enabled: true},
+			Notify:  config.NotifyConfig{Enabled: true, Command: "touch " + escaped},
+		},
+	}
+
+	var out bytes.Buffer
+	if _, err := (&Executor{Stdout: &out, SkipHistory: true}).Run(context.Background(), ctx, nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Warning: failed to send notification: notify command failed") {
+		t.Errorf("expected the notify command to fail, got %q", out.String())
+	}
+	if _, err := os.Stat(escaped); !os.IsNotExist(err) {
+		t.Errorf("expected the notify command to be sandboxed, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// ScheduledRunnable is a runnable declaring a schedule.
+type ScheduledRunnable struct {
+	Collection string
+	Runnable   string
+	Expression string
+	schedule   *cronSchedule
+}
+
+func (s ScheduledRunnable) key() string {
+	return s.Collection + "/" + s.Runnable
+}
+
+// findScheduled loads the schedules of every runnable in every collection.
+// Invalid schedules are returned as errors without hiding the valid ones.
+func findScheduled() ([]ScheduledRunnable, []error) {
+	rootDir, err := getRoot()
+	if err != nil {
+		return nil, []error{err}
+	}
+	entries, err := os.ReadDir(rootDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{fmt.Errorf("failed to list directory: %w", err)}
+	}
+
+	var scheduled []ScheduledRunnable
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		collectionPath := filepath.Join(rootDir, entry.Name())
+		colCfg, err := config.LoadCollectionConfig(collectionPath)
+		if err != nil || colCfg == nil {
+			continue
+		}
+
+		for _, name := range colCfg.Runnables {
+			runCfg, err := config.LoadRunnableConfig(filepath.Join(collectionPath, name))
+			if err != nil || runCfg == nil || runCfg.Schedule == "" || !supportsPlatform(runCfg, currentPlatform) {
+				continue
+			}
+			schedule, err := parseCron(runCfg.Schedule)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s/%s: %w", entry.Name(), name, err))
+				continue
+			}
+			scheduled = append(scheduled, ScheduledRunnable{
+				Collection: entry.Name(),
+				Runnable:   name,
+				Expression: runCfg.Schedule,
+				schedule:   schedule,
+			})
+		}
+	}
+
+	sort.Slice(scheduled, func(i, j int) bool { return scheduled[i].key() < scheduled[j].key() })
+	return scheduled, errs
+}
+
+// ListSchedules prints every scheduled runnable along with its next run.
+func ListSchedules() error {
+	scheduled, errs := findScheduled()
+	for _, err := range errs {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if len(scheduled) == 0 {
+		fmt.Println("No scheduled runnables found.")
+		return nil
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "COLLECTION\tRUNNABLE\tSCHEDULE\tNEXT")
+	for _, s := range scheduled {
+		next := "never"
+		if t := s.schedule.Next(now); !t.IsZero() {
+			next = t.Format(time.DateTime)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Collection, s.Runnable, s.Expression, next)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	return nil
+}
+
+// scheduler starts scheduled runnables and keeps track of the ones still running.
+type scheduler struct {
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+	opts    runOptions
+}
+
+// RunScheduler runs scheduled runnables in the foreground until interrupted.
+// Schedules are reloaded every minute, so edits to collections are picked up
+// without a restart.
+func RunScheduler() error {
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// scheduled runs are unattended: no stdin, and their own process group
+	// so that stopping the scheduler stops them too
+	s := &scheduler{
+		running: make(map[string]bool),
+		opts:    runOptions{stdout: os.Stdout, stderr: os.Stderr, processGroup: true},
+	}
+
+	fmt.Printf("%s scheduler started\n", time.Now().Format(time.DateTime))
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-c.Done():
+			fmt.Printf("%s scheduler stopping, waiting for running runnables\n", time.Now().Format(time.DateTime))
+			s.wg.Wait()
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+		s.tick(c, next)
+	}
+}
+
+// tick starts every runnable scheduled at the minute of t, skipping those whose
+// previous run has not finished yet.
+func (s *scheduler) tick(c context.Context, t time.Time) {
+	scheduled, errs := findScheduled()
+	for _, err := range errs {
+		s.logf(t, "invalid schedule: %v", err)
+	}
+
+	for _, sr := range scheduled {
+		if !sr.schedule.Matches(t) {
+			continue
+		}
+
+		key := sr.key()
+		s.mu.Lock()
+		if s.running[key] {
+			s.mu.Unlock()
+			s.logf(t, "skipping %s: previous run still active", key)
+			continue
+		}
+		s.running[key] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func(sr ScheduledRunnable) {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.running, key)
+				s.mu.Unlock()
+			}()
+			s.run(c, t, sr)
+		}(sr)
+	}
+}
+
+func (s *scheduler) run(c context.Context, t time.Time, sr ScheduledRunnable) {
+	key := sr.key()
+	ctx, err := ResolveCommand(sr.Collection, []string{sr.Runnable})
+	if err != nil {
+		s.logf(t, "failed to resolve %s: %v", key, err)
+		return
+	}
+
+	s.logf(t, "starting %s", key)
+	opts := s.opts
+	opts.result = &Result{}
+	start := time.Now()
+	err = executeRecorded(c, ctx, nil, opts)
+	duration := time.Since(start)
+	// runs stopped along with the scheduler are not reported
+	if !opts.result.UpToDate && c.Err() == nil {
+		notifyRun(ctx, nil, duration, err, s.opts.stdout, s.opts.stderr)
+	}
+	if err != nil {
+		s.logf(time.Now(), "%s failed after %s: %v", key, duration.Round(time.Millisecond), err)
+		return
+	}
+	s.logf(time.Now(), "%s finished in %s", key, duration.Round(time.Millisecond))
+}
+
+func (s *scheduler) logf(t time.Time, format string, args ...any) {
+	_, _ = fmt.Fprintf(s.opts.stdout, "%s scheduler: %s\n", t.Format(time.DateTime), fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Tick(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_HOME", tempDir)
+
+	if err := CreateCollection("col1"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	for name, content := range map[string]string{
+		"cleanup": "schedule: \"*/5 * * * *\"\nrun: sleep 0.5\nnotify:\n  command: echo \"$SHELLICAN_NOTIFY_STATUS\" > notified",
+		"hourly":  "schedule: \"@hourly\"\nrun: \"true\"",
+		"broken":  "schedule: \"not cron\"\nrun: \"true\"",
+	} {
+		if err := CreateRunnable("col1", name); err != nil {
+			t.Fatalf("setup run failed: %v", err)
+		}
+		runPath := filepath.Join(tempDir, ".shellican", "col1", name, "runnable.yml")
+		if err := os.WriteFile(runPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write runnable config: %v", err)
+		}
+	}
+
+	scheduled, errs := findScheduled()
+	if len(scheduled) != 2 || len(errs) != 1 {
+		t.Fatalf("Expected 2 schedules and 1 error, got %v, %v", scheduled, errs)
+	}
+	if err := ListSchedules(); err != nil {
+		t.Errorf("ListSchedules failed: %v", err)
+	}
+
+	s := &scheduler{
+		running: make(map[string]bool),
+		opts:    runOptions{stdout: os.Stdout, stderr: os.Stderr, processGroup: true},
+	}
+	at := time.Date(2026, 10, 19, 9, 5, 0, 0, time.UTC)
+	s.tick(context.Background(), at)
+	// the first run is still sleeping, so it must not overlap
+	s.tick(context.Background(), at)
+	s.wg.Wait()
+
+	entries, err := LoadHistory()
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Runnable != "cleanup" || entries[0].ExitCode != 0 {
+		t.Errorf("Expected a single recorded cleanup run, got %+v", entries)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, ".shellican", "col1", "cleanup", "notified"))
+	if err != nil || string(data) != "success\n" {
+		t.Errorf("Expected the scheduled run to notify, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// scriptInterpreters runs scripts without the executable bit or a shebang, by extension.
+var scriptInterpreters = map[string]string{
+	".sh": shellPath,
+	".py": "python3",
+	".js": "node",
+	".rb": "ruby",
+}
+
+// isExecutable reports whether the file described by info has an executable bit set.
+func isExecutable(info os.FileInfo) bool {
+	return info.Mode().Perm()&0111 != 0
+}
+
+// scriptArgv returns the command line running the script at path. Executable
+// scripts run directly; the others run through the interpreter named by their
+// shebang, or the one their extension is associated with.
+func scriptArgv(path string, args []string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if isExecutable(info) {
+		return append([]string{path}, args...), nil
+	}
+
+	interpreter, err := scriptInterpreter(path)
+	if err != nil {
+		return nil, err
+	}
+	argv := append(interpreter, path)
+	return append(argv, args...), nil
+}
+
+// scriptInterpreter returns the interpreter of a script that is not executable.
+func scriptInterpreter(path string) ([]string, error) {
+	shebang, err := readShebang(path)
+	if err != nil {
+		return nil, err
+	}
+	if shebang != "" {
+		// as the kernel does, everything after the interpreter is a single argument
+		interpreter, arg, _ := strings.Cut(shebang, " ")
+		if arg = strings.TrimSpace(arg); arg != "" {
+			return []string{interpreter, arg}, nil
+		}
+		return []string{interpreter}, nil
+	}
+
+	if interpreter, ok := scriptInterpreters[strings.ToLower(filepath.Ext(path))]; ok {
+		return []string{interpreter}, nil
+	}
+	return nil, fmt.Errorf("script %s is not executable and has no shebang or known extension", filepath.Base(path))
+}
+
+// readShebang returns the interpreter line of the script at path without its #!, or "".
+func readShebang(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && line == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(line, "#!") {
+		return "", nil
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "#!")), nil
+}
+
+// scriptWarnings reports the scripts of the runnables of the collection at
+// colDir that are not executable, along with how they will be run.
+func scriptWarnings(colDir string) []string {
+	colCfg, err := config.LoadCollectionConfig(colDir)
+	if err != nil || colCfg == nil {
+		return nil
+	}
+
+	var warnings []string
+	for _, name := range colCfg.Runnables {
+		runDir := filepath.Join(colDir, name)
+		runCfg, err := config.LoadRunnableConfig(runDir)
+		if err != nil || runCfg == nil {
+			continue
+		}
+
+		var checked []string
+		for _, command := range []string{runCfg.Before, runCfg.Run, runCfg.After} {
+			path, ok := scriptPath(command, runDir)
+			if !ok || slices.Contains(checked, path) {
+				continue
+			}
+			checked = append(checked, path)
+
+			info, err := os.Stat(path)
+			if err != nil || isExecutable(info) {
+				continue
+			}
+			if interpreter, err := scriptInterpreter(path); err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: %v", name, err))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("%s: script %s is not executable, it will run with %s", name, command, strings.Join(interpreter, " ")))
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScriptArgv(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]struct {
+		content string
+		mode    os.FileMode
+	}{
+		"exec":     {"#!/bin/sh\n", 0755},
+		"shebang":  {"#!/usr/bin/env python3 -u\r\nprint(1)\n", 0644},
+		"plain.sh": {"echo hi\n", 0644},
+		"app.PY":   {"print(1)\n", 0644},
+		"unknown":  {"echo hi\n", 0644},
+	}
+	for name, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(f.content), f.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string][]string{
+		"exec":     {filepath.Join(dir, "exec"), "a"},
+		"shebang":  {"/usr/bin/env", "python3 -u", filepath.Join(dir, "shebang"), "a"},
+		"plain.sh": {shellPath, filepath.Join(dir, "plain.sh"), "a"},
+		"app.PY":   {"python3", filepath.Join(dir, "app.PY"), "a"},
+	}
+	for name, want := range tests {
+		got, err := scriptArgv(filepath.Join(dir, name), []string{"a"})
+		if err != nil {
+			t.Errorf("%s: scriptArgv failed: %v", name, err)
+			continue
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+
+	if _, err := scriptArgv(filepath.Join(dir, "unknown"), nil); err == nil || !strings.Contains(err.Error(), "no shebang or known extension") {
+		t.Errorf("expected error for a script without interpreter, got %v", err)
+	}
+}
+
+func TestExecuteOrShell_NonExecutableScript(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.sh"), []byte("echo \"hello $1\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	opts := runOptions{stdout: &stdout, stderr: &stdout}
+	if err := executeOrShell(context.Background(), "main.sh", []string{"world"}, nil, dir, opts); err != nil {
+		t.Fatalf("executeOrShell failed: %v", err)
+	}
+	if stdout.String() != "hello world\n" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+}
+
+func TestScriptWarnings(t *testing.T) {
+	colDir := writeTestRunnables(t, map[string]string{
+		"ok":      "run: main.sh\n",
+		"plain":   "run: main.sh\nbefore: main.sh\n",
+		"unknown": "run: main\n",
+		"inline":  "run: echo hi\n",
+	})
+	scripts := map[string]os.FileMode{"ok/main.sh": 0755, "plain/main.sh": 0644, "unknown/main": 0644}
+	for path, mode := range scripts {
+		if err := os.WriteFile(filepath.Join(colDir, path), []byte("echo hi\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	warnings := scriptWarnings(colDir)
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %q", warnings)
+	}
+	joined := strings.Join(warnings, "\n")
+	if !strings.Contains(joined, "plain: script main.sh is not executable, it will run with /bin/sh") {
+		t.Errorf("expected warning for the shell script, got %q", joined)
+	}
+	if !strings.Contains(joined, "unknown: script main is not executable and has no shebang") {
+		t.Errorf("expected warning for the script without interpreter, got %q", joined)
+	}
+}
@@ -0,0 +1,226 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+const (
+	// defaultServiceBackoff is the delay before the first restart when not configured.
+	defaultServiceBackoff = time.Second
+	// maxServiceBackoff caps the doubling delay between restarts.
+	maxServiceBackoff = time.Minute
+	// serviceStableAfter is how long a run must last for the backoff to start over.
+	serviceStableAfter = time.Minute
+	// readyInterval is the delay between readiness checks.
+	readyInterval = time.Second
+	// defaultReadyTimeout is how long a service gets to become ready when not configured.
+	defaultReadyTimeout = 30 * time.Second
+	// serviceStopTimeout is how long a stopped service gets to run its after hook.
+	serviceStopTimeout = 30 * time.Second
+)
+
+// Service states reported by shellican ps.
+const (
+	ServiceStarting = "starting"
+	ServiceRunning  = "running"
+	ServiceReady    = "ready"
+	ServiceBackoff  = "backoff"
+	ServiceStopping = "stopping"
+)
+
+// detachedIDEnv tells a background run its id, so that a supervisor can report its status.
+const detachedIDEnv = "SHELLICAN_DETACHED_ID"
+
+// ServiceStatus is the state of a supervised background run.
+type ServiceStatus struct {
+	State    string    `json:"state"`
+	Restarts int       `json:"restarts"`
+	Updated  time.Time `json:"updated"`
+}
+
+// isService reports whether the runnable is supervised.
+func isService(cfg *config.RunnableConfig) bool {
+	return cfg.Service != (config.ServiceConfig{})
+}
+
+// takeDetachedID returns the id of the background run this process is, or 0.
+// The variable is removed so that it is not passed on to commands.
+func takeDetachedID() int {
+	id, _ := strconv.Atoi(os.Getenv(detachedIDEnv))
+	_ = os.Unsetenv(detachedIDEnv)
+	return id
+}
+
+func serviceStatusPath(id int) (string, error) {
+	dir, err := getDetachedDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strconv.Itoa(id)+".status"), nil
+}
+
+// reportServiceStatus records the state of the background run of opts, if any.
+func reportServiceStatus(opts runOptions, state string, restarts int) {
+	if opts.detachedID == 0 {
+		return
+	}
+	path, err := serviceStatusPath(opts.detachedID)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(ServiceStatus{State: state, Restarts: restarts, Updated: time.Now()})
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		_, _ = fmt.Fprintf(opts.stdout, "Warning: failed to write service status: %v\n", err)
+	}
+}
+
+// loadServiceStatus returns the last recorded state of the background run id.
+func loadServiceStatus(id int) (*ServiceStatus, error) {
+	path, err := serviceStatusPath(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var status ServiceStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse service status: %w", err)
+	}
+	return &status, nil
+}
+
+// superviseRun runs the command of a service runnable until c is cancelled,
+// restarting it as its restart policy says. Cancellation is a graceful stop
+// and is not reported as an error, so that the after hook still runs.
+func superviseRun(c context.Context, ctx *ExecutionContext, args []string, env []string, opts runOptions) error {
+	svc := ctx.Config.Service
+	switch svc.Restart {
+	case "", "no", "on-failure", "always":
+	default:
+		return fmt.Errorf("invalid service restart policy '%s': expected no, on-failure or always", svc.Restart)
+	}
+
+	// services run unattended, in their own process group so that stopping
+	// the supervisor stops everything they spawned
+	opts.stdin = nil
+	opts.processGroup = true
+
+	initialDelay := svc.Backoff
+	if initialDelay <= 0 {
+		initialDelay = defaultServiceBackoff
+	}
+	delay := initialDelay
+	name := runnableName(ctx)
+
+	for restarts := 0; ; restarts++ {
+		reportServiceStatus(opts, ServiceStarting, restarts)
+		started := time.Now()
+		err := runUntilReady(c, ctx, args, env, opts, restarts)
+
+		if c.Err() != nil {
+			reportServiceStatus(opts, ServiceStopping, restarts)
+			return nil
+		}
+		if svc.Restart == "" || svc.Restart == "no" || (svc.Restart == "on-failure" && err == nil) {
+			return err
+		}
+		if svc.MaxRestarts > 0 && restarts >= svc.MaxRestarts {
+			if err == nil {
+				return fmt.Errorf("service exited, giving up after %d restarts", restarts)
+			}
+			return fmt.Errorf("giving up after %d restarts: %w", restarts, err)
+		}
+
+		if time.Since(started) > serviceStableAfter {
+			delay = initialDelay
+		}
+		outcome := "exited"
+		if err != nil {
+			outcome = fmt.Sprintf("failed: %v", err)
+		}
+		_, _ = fmt.Fprintf(opts.stdout, "\n--- %s %s, restarting in %s ---\n\n", name, outcome, delay)
+		retry := Event{Type: EventRetry, Phase: PhaseRun, Attempt: restarts + 1, DurationMs: delay.Milliseconds()}
+		if err != nil {
+			retry.Error = err.Error()
+		}
+		opts.events.emit(retry)
+		reportServiceStatus(opts, ServiceBackoff, restarts)
+
+		select {
+		case <-c.Done():
+			reportServiceStatus(opts, ServiceStopping, restarts)
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxServiceBackoff)
+	}
+}
+
+// runUntilReady runs the command once while checking its readiness. A command
+// that does not become ready in time is stopped and reported as failed.
+func runUntilReady(c context.Context, ctx *ExecutionContext, args []string, env []string, opts runOptions, restarts int) error {
+	svc := ctx.Config.Service
+	if svc.Ready == "" {
+		reportServiceStatus(opts, ServiceRunning, restarts)
+		return executeOrShell(c, ctx.Config.Run, args, env, ctx.RunnablePath, opts.forRun())
+	}
+
+	runCtx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	notReady := make(chan error, 1)
+	checked := make(chan struct{})
+	go func() {
+		defer close(checked)
+		err := waitReady(runCtx, svc, env, ctx.RunnablePath, opts.quiet())
+		if err == nil {
+			_, _ = fmt.Fprintf(opts.stdout, "--- %s is ready ---\n", runnableName(ctx))
+			reportServiceStatus(opts, ServiceReady, restarts)
+			return
+		}
+		if runCtx.Err() == nil {
+			notReady <- err
+			cancel()
+		}
+	}()
+
+	err := executeOrShell(runCtx, ctx.Config.Run, args, env, ctx.RunnablePath, opts.forRun())
+	cancel()
+	<-checked
+	select {
+	case readyErr := <-notReady:
+		opts.events.emit(Event{Type: EventTimeout, Phase: PhaseRun, Command: svc.Ready, Error: readyErr.Error()})
+		return readyErr
+	default:
+		return err
+	}
+}
+
+// waitReady runs the readiness check of svc with opts until it succeeds, times
+// out or c is cancelled.
+func waitReady(c context.Context, svc config.ServiceConfig, env []string, dir string, opts runOptions) error {
+	timeout := svc.ReadyTimeout
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+	err := poll(c, timeout, readyInterval, func(c context.Context) error {
+		return runShell(c, svc.Ready, nil, env, dir, opts)
+	})
+	if err != nil && c.Err() == nil {
+		return fmt.Errorf("not ready after %s: %s", timeout, svc.Ready)
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestSuperviseRun_RestartsOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	out := &syncBuffer{}
+	ctx := &ExecutionContext{
+		RunnablePath: dir,
+		Config: &config.RunnableConfig{
+			Run: "echo run >> runs; exit 1",
+			Service: config.ServiceConfig{
+				Restart:     "on-failure",
+				Backoff:     10 * time.Millisecond,
+				MaxRestarts: 2,
+			},
+		},
+	}
+
+	err := execute(context.Background(), ctx, nil, runOptions{stdout: out, stderr: out})
+	if err == nil || !strings.Contains(err.Error(), "giving up after 2 restarts") {
+		t.Fatalf("expected to give up after 2 restarts, got %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "runs"))
+	if n := strings.Count(string(data), "run"); n != 3 {
+		t.Errorf("expected 3 runs, got %d", n)
+	}
+	if !strings.Contains(out.String(), "restarting in 20ms") {
+		t.Errorf("expected the backoff to double, got:\n%s", out.String())
+	}
+}
+
+func TestSuperviseRun_NoRestartOnSuccess(t *testing.T) {
+	dir := t.TempDir()
+	ctx := &ExecutionContext{
+		RunnablePath: dir,
+		Config: &config.RunnableConfig{
+			Run:     "echo run >> runs",
+			Service: config.ServiceConfig{Restart: "on-failure", Backoff: 10 * time.Millisecond},
+		},
+	}
+
+	if err := execute(context.Background(), ctx, nil, runOptions{stdout: &syncBuffer{}, stderr: &syncBuffer{}}); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "runs"))
+	if n := strings.Count(string(data), "run"); n != 1 {
+		t.Errorf("expected a single run, got %d", n)
+	}
+}
+
+func TestSuperviseRun_GracefulStopRunsAfterHook(t *testing.T) {
+	dir := t.TempDir()
+	out := &syncBuffer{}
+	ctx := &ExecutionContext{
+		RunnablePath: dir,
+		Config: &config.RunnableConfig{
+			Run:   "touch ready; exec sleep 30",
+			After: "echo after > after.out",
+			Service: config.ServiceConfig{
+				Restart: "always",
+				Ready:   "test -f ready",
+			},
+		},
+	}
+
+	c, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- execute(c, ctx, nil, runOptions{stdout: out, stderr: out}) }()
+
+	waitFor(t, "service to be ready", func() bool { return strings.Contains(out.String(), "is ready") })
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("expected graceful stop, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("service did not stop")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "after.out")); err != nil {
+		t.Errorf("expected the after hook to run on stop: %v", err)
+	}
+}
+
+func TestSuperviseRun_NotReady(t *testing.T) {
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Run: "exec sleep 30",
+			Service: config.ServiceConfig{
+				Ready:        "false",
+				ReadyTimeout: 10 * time.Millisecond,
+			},
+		},
+	}
+
+	err := execute(context.Background(), ctx, nil, runOptions{stdout: &syncBuffer{}, stderr: &syncBuffer{}})
+	if err == nil || !strings.Contains(err.Error(), "not ready after") {
+		t.Fatalf("expected readiness failure, got %v", err)
+	}
+}
+
+func TestSuperviseRun_InvalidPolicy(t *testing.T) {
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config:       &config.RunnableConfig{Run: "true", Service: config.ServiceConfig{Restart: "sometimes"}},
+	}
+	if err := execute(context.Background(), ctx, nil, runOptions{stdout: &syncBuffer{}, stderr: &syncBuffer{}}); err == nil {
+		t.Fatal("expected error for invalid restart policy")
+	}
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// TemplateData is what the run command and hooks of a runnable with
+// `template: true` are rendered with.
+type TemplateData struct {
+	Collection string
+	Runnable   string
+	Name       string
+	Dir        string
+	Args       []string
+	Env        map[string]string
+	OS         string
+	Arch       string
+	Hostname   string
+}
+
+// templateFuncs are the helpers available to command templates.
+var templateFuncs = template.FuncMap{
+	"quote":    shellQuote,
+	"quoteAll": shellQuoteAll,
+}
+
+// shellQuote quotes s as a single shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellQuoteAll quotes every element of words and joins them with spaces.
+func shellQuoteAll(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = shellQuote(w)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// templatedCommand is a command of a runnable that is rendered.
+type templatedCommand struct {
+	phase   string
+	command *string
+}
+
+// templatedCommands returns the rendered commands of cfg, in the order they run.
+func templatedCommands(cfg *config.RunnableConfig) []templatedCommand {
+	return []templatedCommand{
+		{PhaseBefore, &cfg.Before},
+		{PhaseRun, &cfg.Run},
+		{PhaseAfter, &cfg.After},
+	}
+}
+
+// parseCommand parses the template of the command of phase.
+func parseCommand(phase, command string) (*template.Template, error) {
+	tmpl, err := template.New(phase).Funcs(templateFuncs).Option("missingkey=error").Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", phase, err)
+	}
+	return tmpl, nil
+}
+
+// checkTemplates reports syntax errors in the command templates of cfg.
+func checkTemplates(cfg *config.RunnableConfig) error {
+	if !cfg.Template {
+		return nil
+	}
+	for _, tc := range templatedCommands(cfg) {
+		if _, err := parseCommand(tc.phase, *tc.command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderTemplates returns ctx with its run command and hooks rendered, or ctx
+// itself when the runnable is not templated. Missing keys are errors.
+func renderTemplates(ctx *ExecutionContext, args []string) (*ExecutionContext, error) {
+	if !ctx.Config.Template {
+		return ctx, nil
+	}
+
+	hostname, _ := os.Hostname()
+	data := TemplateData{
+		Collection: ctx.Collection,
+		Runnable:   runnableName(ctx),
+		Name:       ctx.Config.Name,
+		Dir:        ctx.RunnablePath,
+		Args:       args,
+		Env:        envMap(ctx),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Hostname:   hostname,
+	}
+
+	cfg := *ctx.Config
+	for _, tc := range templatedCommands(&cfg) {
+		tmpl, err := parseCommand(tc.phase, *tc.command)
+		if err != nil {
+			return nil, err
+		}
+		var rendered strings.Builder
+		if err := tmpl.Execute(&rendered, data); err != nil {
+			return nil, fmt.Errorf("failed to render %s template: %w", tc.phase, err)
+		}
+		*tc.command = rendered.String()
+	}
+
+	rendered := *ctx
+	rendered.Config = &cfg
+	return &rendered, nil
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":      "'plain'",
+		"with space": "'with space'",
+		"it's":       `'it'\''s'`,
+		"":           "''",
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := shellQuoteAll([]string{"a b", "c"}); got != "'a b' 'c'" {
+		t.Errorf("unexpected quoteAll result %q", got)
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	ctx := &ExecutionContext{
+		Collection:   "col",
+		Runnable:     "deploy",
+		RunnablePath: t.TempDir(),
+		Config: &config.RunnableConfig{
+			Name:     "Deploy",
+			Template: true,
+			Before:   "echo {{.Collection}}/{{.Runnable}} {{.Name}}",
+			Run:      "echo {{.Env.TARGET}} {{quoteAll .Args}} {{index .Args 0 | quote}}",
+			After:    "echo {{.OS}}/{{.Arch}}",
+		},
+		Environments: map[string]string{"TARGET": "staging"},
+	}
+
+	rendered, err := renderTemplates(ctx, []string{"it's", "b"})
+	if err != nil {
+		t.Fatalf("renderTemplates failed: %v", err)
+	}
+	if rendered.Config.Before != "echo col/deploy Deploy" {
+		t.Errorf("unexpected before %q", rendered.Config.Before)
+	}
+	if want := `echo staging 'it'\''s' 'b' 'it'\''s'`; rendered.Config.Run != want {
+		t.Errorf("expected run %q, got %q", want, rendered.Config.Run)
+	}
+	if want := "echo " + runtime.GOOS + "/" + runtime.GOARCH; rendered.Config.After != want {
+		t.Errorf("expected after %q, got %q", want, rendered.Config.After)
+	}
+	if !strings.Contains(ctx.Config.Run, "{{") {
+		t.Error("expected the context not to be modified")
+	}
+
+	ctx.Config.Run = "echo {{.Env.SHELLICAN_TEMPLATE_UNSET}}"
+	if _, err := renderTemplates(ctx, nil); err == nil || !strings.Contains(err.Error(), "SHELLICAN_TEMPLATE_UNSET") {
+		t.Errorf("expected missing key error, got %v", err)
+	}
+
+	ctx.Config.Template = false
+	if rendered, err := renderTemplates(ctx, nil); err != nil || rendered != ctx {
+		t.Errorf("expected untemplated runnable to be left as is, got %v", err)
+	}
+}
+
+func TestExecute_Template(t *testing.T) {
+	writeTestRunnables(t, map[string]string{
+		"tmpl":   "template: true\nrun: 'printf \"%s\\n\" {{quoteAll .Args}}'\n",
+		"plain":  "run: \"echo '{{.Args}}'\"\n",
+		"broken": "template: true\nrun: 'echo {{.Args'\n",
+	})
+
+	ctx, err := ResolveCommand("col", []string{"tmpl"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	var stdout bytes.Buffer
+	if _, err := (&Executor{Stdout: &stdout, SkipHistory: true}).Run(context.Background(), ctx, []string{"a b", "$HOME"}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if stdout.String() != "a b\n$HOME\n" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+
+	ctx, err = ResolveCommand("col", []string{"plain"})
+	if err != nil {
+		t.Fatalf("ResolveCommand failed: %v", err)
+	}
+	stdout.Reset()
+	if _, err := (&Executor{Stdout: &stdout, SkipHistory: true}).Run(context.Background(), ctx, nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if stdout.String() != "{{.Args}}\n" {
+		t.Errorf("expected untemplated command to run verbatim, got %q", stdout.String())
+	}
+
+	if _, err := ResolveCommand("col", []string{"broken"}); err == nil || !strings.Contains(err.Error(), "invalid run template") {
+		t.Errorf("expected template syntax error at resolution, got %v", err)
+	}
+}
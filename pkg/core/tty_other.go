@@ -0,0 +1,21 @@
+//go:build !linux && !darwin
+
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// isTerminal reports whether r is connected to a terminal. Terminals are not
+// detected on this platform, so prompts and confirmations need --yes or values
+// set upfront.
+func isTerminal(r io.Reader) bool {
+	return false
+}
+
+// disableEcho is not supported on this platform.
+func disableEcho(f *os.File) (func(), error) {
+	return nil, fmt.Errorf("hiding input is not supported on this platform")
+}
@@ -0,0 +1,38 @@
+//go:build linux || darwin
+
+package core
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal reports whether r is connected to a terminal.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(ioctlReadTermios), uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
+
+// disableEcho stops the terminal f from echoing input and returns a function
+// restoring its previous settings.
+func disableEcho(f *os.File) (func(), error) {
+	var termios syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(ioctlReadTermios), uintptr(unsafe.Pointer(&termios))); errno != 0 {
+		return nil, errno
+	}
+	saved := termios
+	termios.Lflag &^= syscall.ECHO
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(ioctlWriteTermios), uintptr(unsafe.Pointer(&termios))); errno != 0 {
+		return nil, errno
+	}
+	return func() {
+		_, _, _ = syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(ioctlWriteTermios), uintptr(unsafe.Pointer(&saved)))
+	}, nil
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+const (
+	// defaultWaitTimeout is how long a wait_for condition is polled when not configured.
+	defaultWaitTimeout = 30 * time.Second
+	// defaultWaitInterval is the delay between two checks of a wait_for condition.
+	defaultWaitInterval = time.Second
+)
+
+// waitConditions blocks until every condition holds, in order, or fails with
+// the first one that does not hold within its timeout.
+func waitConditions(c context.Context, ctx *ExecutionContext, env []string, opts runOptions) error {
+	for _, cond := range ctx.Config.WaitFor {
+		check, desc, err := waitCheck(cond, ctx.RunnablePath, env, opts.quiet())
+		if err != nil {
+			return err
+		}
+
+		timeout := cond.Timeout
+		if timeout <= 0 {
+			timeout = defaultWaitTimeout
+		}
+		interval := cond.Interval
+		if interval <= 0 {
+			interval = defaultWaitInterval
+		}
+
+		waiting := false
+		err = poll(c, timeout, interval, func(c context.Context) error {
+			err := check(c)
+			if err != nil && !waiting {
+				waiting = true
+				_, _ = fmt.Fprintf(opts.stdout, "Waiting for %s...\n", desc)
+			}
+			return err
+		})
+		if err != nil {
+			err = fmt.Errorf("gave up waiting for %s: %w", desc, err)
+			if c.Err() == nil {
+				opts.events.emit(Event{Type: EventTimeout, Phase: PhaseWait, Command: desc, Error: err.Error()})
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// waitCheck returns the check of a condition along with its description.
+// Commands are probed with opts.
+func waitCheck(cond config.WaitCondition, dir string, env []string, opts runOptions) (func(context.Context) error, string, error) {
+	set := 0
+	for _, v := range []string{cond.TCP, cond.File, cond.HTTP, cond.Command} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, "", fmt.Errorf("invalid wait_for entry: expected exactly one of tcp, file, http or command")
+	}
+
+	switch {
+	case cond.TCP != "":
+		return func(c context.Context) error {
+			var d net.Dialer
+			conn, err := d.DialContext(c, "tcp", cond.TCP)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}, "tcp " + cond.TCP, nil
+
+	case cond.File != "":
+		path := cond.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		return func(context.Context) error {
+			_, err := os.Stat(path)
+			return err
+		}, "file " + cond.File, nil
+
+	case cond.HTTP != "":
+		return func(c context.Context) error {
+			req, err := http.NewRequestWithContext(c, http.MethodGet, cond.HTTP, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("got status %s", resp.Status)
+			}
+			return nil
+		}, "http " + cond.HTTP, nil
+
+	default:
+		return func(c context.Context) error {
+			return runShell(c, cond.Command, nil, env, dir, opts)
+		}, "command " + cond.Command, nil
+	}
+}
+
+// poll calls check every interval until it succeeds, returning the last error
+// once timeout has elapsed or c is cancelled. A check may take until the
+// deadline, or interval if that is longer.
+func poll(c context.Context, timeout, interval time.Duration, check func(context.Context) error) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		checkCtx, cancel := context.WithTimeout(c, max(time.Until(deadline), interval))
+		err := check(checkCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if c.Err() != nil {
+			return c.Err()
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-c.Done():
+			return c.Err()
+		case <-time.After(interval):
+		}
+	}
+}
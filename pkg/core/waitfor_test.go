@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+func waitContext(dir string, conds ...config.WaitCondition) *ExecutionContext {
+	return &ExecutionContext{
+		RunnablePath: dir,
+		Config:       &config.RunnableConfig{Run: "true", WaitFor: conds},
+	}
+}
+
+func TestWaitConditions_File(t *testing.T) {
+	dir := t.TempDir()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.WriteFile(filepath.Join(dir, "ready"), nil, 0644)
+	}()
+
+	out := &syncBuffer{}
+	ctx := waitContext(dir, config.WaitCondition{File: "ready", Interval: 10 * time.Millisecond})
+	if err := waitConditions(context.Background(), ctx, nil, runOptions{stdout: out}); err != nil {
+		t.Fatalf("waitConditions failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Waiting for file ready...") {
+		t.Errorf("expected waiting message, got %q", out.String())
+	}
+}
+
+func TestWaitConditions_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	ctx := waitContext(t.TempDir(), config.WaitCondition{TCP: ln.Addr().String(), Interval: 10 * time.Millisecond})
+	if err := waitConditions(context.Background(), ctx, nil, runOptions{stdout: &syncBuffer{}}); err != nil {
+		t.Fatalf("waitConditions failed: %v", err)
+	}
+}
+
+func TestWaitConditions_HTTP(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := waitContext(t.TempDir(), config.WaitCondition{HTTP: srv.URL, Interval: 10 * time.Millisecond})
+	if err := waitConditions(context.Background(), ctx, nil, runOptions{stdout: &syncBuffer{}}); err != nil {
+		t.Fatalf("waitConditions failed: %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestWaitConditions_CommandUsesEnv(t *testing.T) {
+	ctx := waitContext(t.TempDir(), config.WaitCondition{Command: `test "$READY" = yes`})
+	if err := waitConditions(context.Background(), ctx, []string{"READY=yes"}, runOptions{stdout: &syncBuffer{}}); err != nil {
+		t.Fatalf("waitConditions failed: %v", err)
+	}
+}
+
+func TestWaitConditions_Timeout(t *testing.T) {
+	ctx := waitContext(t.TempDir(),
+		config.WaitCondition{Command: "true"},
+		config.WaitCondition{File: "missing", Timeout: 30 * time.Millisecond, Interval: 10 * time.Millisecond},
+	)
+	err := waitConditions(context.Background(), ctx, nil, runOptions{stdout: &syncBuffer{}})
+	if err == nil || !strings.Contains(err.Error(), "gave up waiting for file missing") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+}
+
+func TestWaitConditions_Invalid(t *testing.T) {
+	for _, cond := range []config.WaitCondition{{}, {TCP: "localhost:1", File: "x"}} {
+		ctx := waitContext(t.TempDir(), cond)
+		if err := waitConditions(context.Background(), ctx, nil, runOptions{stdout: &syncBuffer{}}); err == nil {
+			t.Errorf("expected error for %+v", cond)
+		}
+	}
+}
+
+func TestExecute_WaitsBeforeRun(t *testing.T) {
+	dir := t.TempDir()
+	ctx := waitContext(dir, config.WaitCondition{File: "missing", Timeout: 10 * time.Millisecond, Interval: 5 * time.Millisecond})
+	ctx.Config.Run = "touch ran"
+
+	if err := execute(context.Background(), ctx, nil, runOptions{stdout: &syncBuffer{}, stderr: &syncBuffer{}}); err == nil {
+		t.Fatal("expected execute to fail when the condition does not hold")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); !os.IsNotExist(err) {
+		t.Error("expected run not to start")
+	}
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// watchDebounce is how long the watcher waits for a burst of changes to settle.
+const watchDebounce = 300 * time.Millisecond
+
+// fileWatcher reports paths that changed below the watched directories.
+type fileWatcher interface {
+	Events() <-chan string
+	Errors() <-chan error
+	Close() error
+}
+
+// WatchContext runs a runnable and restarts it whenever one of its watched files
+// changes, until the process is interrupted.
+func WatchContext(ctx *ExecutionContext, args []string) error {
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return watch(c, ctx, args, defaultRunOptions())
+}
+
+// watchPatterns returns the paths to watch, falling back to the sources of the runnable.
+func watchPatterns(cfg *config.RunnableConfig) []string {
+	if len(cfg.Watch) > 0 {
+		return cfg.Watch
+	}
+	return cfg.Sources
+}
+
+// watchRoots returns the existing directories that contain everything patterns can match.
+func watchRoots(baseDir string, patterns []string) []string {
+	seen := make(map[string]bool)
+	var roots []string
+	for _, pattern := range patterns {
+		rel, err := relativePattern(baseDir, pattern)
+		if err != nil {
+			continue
+		}
+		root, _ := globRoot(baseDir, rel)
+		for {
+			info, err := os.Stat(root)
+			if err == nil && info.IsDir() {
+				break
+			}
+			parent := filepath.Dir(root)
+			if parent == root {
+				break
+			}
+			root = parent
+		}
+		if !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+func watch(c context.Context, ctx *ExecutionContext, args []string, opts runOptions) error {
+	patterns := watchPatterns(ctx.Config)
+	if len(patterns) == 0 {
+		return fmt.Errorf("nothing to watch: declare 'watch' or 'sources' in runnable.yml")
+	}
+
+	if err := confirmRun(ctx, opts); err != nil {
+		return err
+	}
+	// confirmed once for the whole session, and restarts must never be skipped as up to date
+	watched := *ctx
+	watched.AssumeYes = true
+	watched.Force = true
+
+	w, err := newFileWatcher(watchRoots(ctx.RunnablePath, patterns))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = w.Close() }()
+
+	// commands run in their own process group so a restart stops everything they
+	// spawned; outside the foreground group they cannot read the terminal
+	opts.processGroup = true
+	opts.stdin = nil
+
+	for {
+		runCtx, cancel := context.WithCancel(c)
+		done := make(chan error, 1)
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			start := time.Now()
+			err := executeRecorded(runCtx, &watched, args, opts)
+			// runs stopped by a change or an interrupt are not reported
+			if runCtx.Err() == nil {
+				notifyRun(&watched, nil, time.Since(start), err, opts.stdout, opts.stderr)
+			}
+			done <- err
+		}()
+
+		trigger, err := waitForChange(c, w, ctx.RunnablePath, patterns, done, opts)
+		cancel()
+		<-finished
+		if err != nil || trigger == "" || c.Err() != nil {
+			return err
+		}
+
+		rel, relErr := filepath.Rel(ctx.RunnablePath, trigger)
+		if relErr != nil {
+			rel = trigger
+		}
+		_, _ = fmt.Fprintf(opts.stdout, "\n--- %s changed, restarting %s ---\n\n", rel, runnableName(ctx))
+	}
+}
+
+// waitForChange blocks until a watched file changes and returns its path.
+// It returns an empty path when c is cancelled. The outcome of the current
+// run is reported when it arrives on done.
+func waitForChange(c context.Context, w fileWatcher, baseDir string, patterns []string, done chan error, opts runOptions) (string, error) {
+	for {
+		select {
+		case <-c.Done():
+			return "", nil
+		case err := <-done:
+			if err != nil {
+				_, _ = fmt.Fprintf(opts.stdout, "\n--- failed: %v, waiting for changes ---\n", err)
+			} else {
+				_, _ = fmt.Fprintf(opts.stdout, "\n--- finished, waiting for changes ---\n")
+			}
+			done = nil
+		case err := <-w.Errors():
+			return "", fmt.Errorf("watcher failed: %w", err)
+		case path, ok := <-w.Events():
+			if !ok {
+				return "", fmt.Errorf("watcher stopped")
+			}
+			if !matchesAny(baseDir, patterns, path) {
+				continue
+			}
+			return path, debounce(c, w)
+		}
+	}
+}
+
+// debounce waits until no change was reported for watchDebounce.
+func debounce(c context.Context, w fileWatcher) error {
+	timer := time.NewTimer(watchDebounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.Done():
+			return nil
+		case <-timer.C:
+			return nil
+		case _, ok := <-w.Events():
+			if !ok {
+				return fmt.Errorf("watcher stopped")
+			}
+			timer.Reset(watchDebounce)
+		}
+	}
+}
+
+func matchesAny(baseDir string, patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(baseDir, pattern, path) {
+			return true
+		}
+	}
+	return false
+}
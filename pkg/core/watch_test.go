@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brsyuksel/shellican/pkg/config"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestWatch_RestartsOnChange(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_HOME", tempDir)
+	runDir := filepath.Join(tempDir, "run")
+	if err := os.MkdirAll(filepath.Join(runDir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create runnable dir: %v", err)
+	}
+
+	ctx := &ExecutionContext{
+		RunnablePath: runDir,
+		Config: &config.RunnableConfig{
+			// the long sleep must be killed on restart
+			Run:   "echo run >> runs.log; sleep 30",
+			Watch: []string{"src/**/*.txt"},
+		},
+		Environments: map[string]string{},
+	}
+
+	runs := func() int {
+		data, _ := os.ReadFile(filepath.Join(runDir, "runs.log"))
+		return strings.Count(string(data), "run")
+	}
+
+	c, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- watch(c, ctx, nil, runOptions{stdout: out, stderr: out})
+	}()
+
+	waitFor(t, "first run", func() bool { return runs() == 1 })
+
+	// not matching the pattern
+	if err := os.WriteFile(filepath.Join(runDir, "src", "ignored.md"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, "src", "input.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	waitFor(t, "restart", func() bool { return runs() == 2 })
+
+	if !strings.Contains(out.String(), "src/input.txt changed") {
+		t.Errorf("Expected separator naming the trigger file, got %q", out.String())
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("watch failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("watch did not stop after cancellation")
+	}
+}
+
+func TestWatch_NothingToWatch(t *testing.T) {
+	ctx := &ExecutionContext{
+		RunnablePath: t.TempDir(),
+		Config:       &config.RunnableConfig{Run: "true"},
+	}
+	if err := watch(context.Background(), ctx, nil, defaultRunOptions()); err == nil {
+		t.Error("Expected error without watch paths")
+	}
+}
+
+func TestWatch_NotifiesEachRun(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SHELLICAN_HOME", tempDir)
+	runDir := filepath.Join(tempDir, "run")
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		t.Fatalf("Failed to create runnable dir: %v", err)
+	}
+
+	ctx := &ExecutionContext{
+		RunnablePath: runDir,
+		Config: &config.RunnableConfig{
+			Run:    "exit 2",
+			Watch:  []string{"*.txt"},
+			Notify: config.NotifyConfig{Enabled: true, Command: `echo "$SHELLICAN_NOTIFY_STATUS" >> notified`},
+		},
+		Environments: map[string]string{},
+	}
+	notified := func() string {
+		data, _ := os.ReadFile(filepath.Join(runDir, "notified"))
+		return string(data)
+	}
+
+	c, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- watch(c, ctx, nil, runOptions{stdout: out, stderr: out})
+	}()
+
+	waitFor(t, "first notification", func() bool { return notified() == "failure\n" })
+	if err := os.WriteFile(filepath.Join(runDir, "input.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	waitFor(t, "second notification", func() bool { return notified() == "failure\nfailure\n" })
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("watch failed: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+//go:build !linux
+
+package core
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// pollInterval is how often the polling watcher scans for changes.
+const pollInterval = 500 * time.Millisecond
+
+// pollingWatcher detects changes by comparing modification times, for
+// platforms without inotify.
+type pollingWatcher struct {
+	roots  []string
+	events chan string
+	errors chan error
+	done   chan struct{}
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// newFileWatcher watches every file below roots.
+func newFileWatcher(roots []string) (fileWatcher, error) {
+	w := &pollingWatcher{
+		roots:  roots,
+		events: make(chan string),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+	go w.pollLoop()
+	return w, nil
+}
+
+func (w *pollingWatcher) Events() <-chan string { return w.events }
+
+func (w *pollingWatcher) Errors() <-chan error { return w.errors }
+
+func (w *pollingWatcher) Close() error {
+	close(w.done)
+	return nil
+}
+
+func (w *pollingWatcher) snapshot() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, root := range w.roots {
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+			}
+			return nil
+		})
+	}
+	return stamps
+}
+
+func (w *pollingWatcher) pollLoop() {
+	defer close(w.events)
+
+	previous := w.snapshot()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		current := w.snapshot()
+		var changed []string
+		for path, stamp := range current {
+			if old, ok := previous[path]; !ok || old != stamp {
+				changed = append(changed, path)
+			}
+		}
+		for path := range previous {
+			if _, ok := current[path]; !ok {
+				changed = append(changed, path)
+			}
+		}
+		previous = current
+
+		for _, path := range changed {
+			select {
+			case w.events <- path:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}